@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/leonelquinteros/thtml/templates"
@@ -11,18 +13,102 @@ func build() {
 	// Load comma separated extensions list
 	exts := strings.Split(_exts, ",")
 
-	// Load templates
-	tpl, err := templates.Load(_templatesPath, exts...)
+	if _errorsFormat != "text" && _errorsFormat != "json" {
+		log.Fatalf("Unknown errors format '%s'", _errorsFormat)
+	}
+
+	// Ignore rules
+	rules, err := ignoreRules()
 	if err != nil {
-		log.Fatalf("Error loading templates from '%s': %s", _templatesPath, err)
+		buildFailed(err, "Error loading ignore rules")
+	}
+
+	// Load theme and comma separated templates directories, later ones override earlier ones
+	tpl := templates.New(exts...)
+	tpl.Ignore(rules)
+	err = tpl.LoadDirs(templatesDirs()...)
+	if err != nil {
+		buildFailed(err, fmt.Sprintf("Error loading templates from '%s'", _templatesPath))
 	}
 
 	// Configure
 	tpl.Minify(_minify)
+	tpl.MinifyOptions(_config.Minify)
+	tpl.Cache(_cachePath)
+	tpl.Drafts(_drafts)
+	tpl.Future(_future)
+	tpl.Expired(_expired)
+	tpl.SCSSIncludePaths(_config.SCSS.IncludePaths...)
+	tpl.KeepGoing(_keepGoing)
+	err = tpl.Bundles(_config.Bundles)
+	if err != nil {
+		buildFailed(err, "Error configuring bundles")
+	}
+	err = tpl.Redirects(_config.Redirects)
+	if err != nil {
+		buildFailed(err, "Error configuring redirects")
+	}
+	err = tpl.RedirectFiles(_config.RedirectFiles...)
+	if err != nil {
+		buildFailed(err, "Error configuring redirect files")
+	}
+	err = tpl.Compress(strings.Split(_compress, ","), _compressLevel, _compressMinSize)
+	if err != nil {
+		buildFailed(err, "Error configuring compression")
+	}
+
+	// Dry run in memory
+	if _dryRun || _diff {
+		out := new(templates.MemFS)
+		err = tpl.BuildFS(publicFS(), out)
+		if err != nil {
+			buildFailed(err, fmt.Sprintf("Error compiling templates from '%s'", _publicPath))
+		}
 
-	// Build
-	err = tpl.Build(_publicPath, _outputPath)
+		changes, err := templates.CompareFS(os.DirFS(_outputPath), out)
+		if err != nil {
+			buildFailed(err, fmt.Sprintf("Error comparing build output '%s'", _outputPath))
+		}
+		printChanges(os.Stdout, changes, _diff)
+		return
+	}
+
+	// Build, replacing the output only on success
+	tpl.KeepBuilds(_keepBuilds)
+	err = tpl.BuildDir(publicFS(), _outputPath)
+	if err != nil {
+		buildFailed(err, fmt.Sprintf("Error compiling templates from '%s' to '%s'", _publicPath, _outputPath))
+	}
+
+	// Report
+	printReport(os.Stdout, tpl.Manifest(), exts)
+}
+
+// rollback replaces the output directory with its newest previous build.
+func rollback() {
+	prev, err := templates.Rollback(_outputPath)
 	if err != nil {
-		log.Fatalf("Error compiling templates from '%s' to '%s': %s", _publicPath, _outputPath, err)
+		log.Fatalf("%s", err)
 	}
+
+	fmt.Printf("Rolled back '%s' to '%s'\n", _outputPath, prev)
+}
+
+// buildFailed prints the errors of a failed build and exits with a non-zero status.
+// A single error is logged with the provided message, unless errors are requested in json format,
+// where it's printed as a build error including the message, located from it when possible.
+func buildFailed(err error, msg string) {
+	if _errorsFormat == "json" {
+		if _, ok := err.(templates.BuildErrors); !ok {
+			err = templates.NewBuildErrors([]templates.BuildError{templates.NewBuildError("", templates.NewError(msg+": "+err.Error()))})
+		}
+		printErrors(os.Stdout, err, _errorsFormat)
+		os.Exit(1)
+	}
+	if _, ok := err.(templates.BuildErrors); !ok {
+		log.Fatalf("%s: %s", msg, err)
+	}
+
+	printErrors(os.Stderr, err, _errorsFormat)
+	os.Exit(1)
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+// config is the project configuration file format.
+type config struct {
+	// Theme is the name of the theme installed in the -themes directory used by the project.
+	Theme string `json:"theme"`
+
+	// Minify configures the minifiers used when -minify is enabled.
+	Minify templates.MinifyOptions `json:"minify"`
+
+	// Bundles lists the public files concatenated into each bundle, by output name.
+	Bundles map[string][]string `json:"bundles"`
+
+	// SCSS configures the compilation of ".scss" stylesheets.
+	SCSS scssConfig `json:"scss"`
+
+	// Redirects lists the redirect rules of moved pages.
+	Redirects []templates.Redirect `json:"redirects"`
+
+	// RedirectFiles lists the formats of the redirect rule files written to the build output: netlify, nginx or apache.
+	RedirectFiles []string `json:"redirectFiles"`
+
+	// Ignore lists patterns of files of the public and templates directories to leave out, in gitignore syntax.
+	Ignore []string `json:"ignore"`
+}
+
+// scssConfig is the SCSS compilation configuration.
+type scssConfig struct {
+	// IncludePaths are the directories where imports are resolved when not found relative to the importing file.
+	IncludePaths []string `json:"includePaths"`
+}
+
+// defaultConfig returns the configuration used when no config file exists.
+func defaultConfig() config {
+	return config{
+		Minify: templates.DefaultMinifyOptions(),
+	}
+}
+
+// loadConfig reads the JSON config file at fn over the default configuration.
+// When a theme is selected, its configuration file is read first, so the project overrides the theme defaults.
+// A missing file isn't an error.
+func loadConfig(fn string) (config, error) {
+	c := defaultConfig()
+
+	content, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(content, &c)
+	if err != nil || c.Theme == "" {
+		return c, err
+	}
+
+	// Theme defaults
+	theme := defaultConfig()
+	themeContent, err := ioutil.ReadFile(filepath.Join(themeDir(c.Theme), themeConfig))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(themeContent, &theme)
+	if err != nil {
+		return c, err
+	}
+	theme.Theme = c.Theme
+
+	// Project overrides
+	err = json.Unmarshal(content, &theme)
+	return theme, err
+}
+
+// ignoreRules returns the rules of the ignore file next to the config file, followed by the config ignore patterns.
+// A missing ignore file isn't an error.
+func ignoreRules() (*templates.IgnoreRules, error) {
+	rules, err := templates.NewIgnoreRules()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(filepath.Dir(_configPath), templates.IgnoreFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = rules.Add(strings.Split(string(content), "\n")...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rules.Add(_config.Ignore...)
+	return rules, err
+}
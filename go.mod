@@ -1,6 +1,9 @@
 module github.com/leonelquinteros/thtml
 
 require (
+	github.com/andybalholm/brotli v1.0.6
+	github.com/evanw/esbuild v0.28.2
 	github.com/leonelquinteros/gorand v1.0.0
-	github.com/tdewolff/minify/v2 v2.3.8
+	github.com/tdewolff/minify/v2 v2.11.2
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8
 )
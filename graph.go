@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// graph executes the "graph" command with the provided arguments:
+// it prints the dependency graph of the templates and pages, the pages using a template, or the orphaned templates.
+func graph(args []string) {
+	cmd := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := cmd.String("format", "dot", "Sets the graph output format: dot, mermaid or json.")
+	usedBy := cmd.String("used-by", "", "Lists the pages using the provided template or define block, directly or through other templates.")
+	orphans := cmd.Bool("orphans", false, "Lists the templates and define blocks never used by other templates or pages.")
+	cmd.Parse(args)
+
+	tpl, roots := sourceService()
+	g, err := tpl.Graph(roots...)
+	if err != nil {
+		log.Fatalf("Error analysing templates from '%s': %s", _templatesPath, err)
+	}
+
+	switch {
+	case *usedBy != "":
+		if !g.Has(*usedBy) {
+			log.Fatalf("Template not found: %s", *usedBy)
+		}
+		for _, page := range g.UsedBy(*usedBy) {
+			fmt.Println(page)
+		}
+
+	case *orphans:
+		for _, name := range g.Orphans() {
+			fmt.Println(name)
+		}
+
+	case *format == "dot":
+		err = g.WriteDOT(os.Stdout)
+
+	case *format == "mermaid":
+		err = g.WriteMermaid(os.Stdout)
+
+	case *format == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(g)
+
+	default:
+		log.Fatalf("Unknown graph format '%s'", *format)
+	}
+	if err != nil {
+		log.Fatalf("Error writing graph: %s", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+// lint executes the "lint" command with the provided arguments: it checks the templates and pages without executing them,
+// prints the issues found and exits with a non-zero status when any of them is an error.
+func lint(args []string) {
+	cmd := flag.NewFlagSet("lint", flag.ExitOnError)
+	format := cmd.String("errors-format", _errorsFormat, "Sets the format of the lint issues: text or json.")
+	cmd.Parse(args)
+	if cmd.NArg() > 0 {
+		log.Fatalf("Unexpected lint arguments: %s", strings.Join(cmd.Args(), " "))
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown errors format '%s'", *format)
+	}
+
+	tpl, roots := sourceService()
+	issues, err := tpl.Lint(roots...)
+	if err != nil {
+		log.Fatalf("Error linting templates from '%s': %s", _templatesPath, err)
+	}
+
+	if printLint(os.Stdout, issues, *format) > 0 {
+		os.Exit(1)
+	}
+}
+
+// sourceService returns a Service configured to analyse the templates and pages without executing them,
+// and the theme and templates directories, later ones override earlier ones.
+func sourceService() (*templates.Service, []fs.FS) {
+	rules, err := ignoreRules()
+	if err != nil {
+		log.Fatalf("Error loading ignore rules: %s", err)
+	}
+
+	tpl := templates.New(strings.Split(_exts, ",")...)
+	tpl.Ignore(rules)
+	tpl.PublicFS(publicFS())
+
+	dirs := templatesDirs()
+	roots := make([]fs.FS, len(dirs))
+	for i, dir := range dirs {
+		roots[i] = os.DirFS(dir)
+	}
+
+	return tpl, roots
+}
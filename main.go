@@ -11,14 +11,65 @@
 //  -build
 //	    Build the assets from the [-public] directory to the [-output] directory by parsing the [-templates] directory.
 //
+//  -rollback
+//	    Replace the [-output] directory with its newest previous build kept by [-keep-builds].
+//
 //  -run
 //	    Run development webserver listening to [-listen] to build pages on-the-fly.
 //
+//  graph [-format dot|mermaid|json] [-used-by <template>] [-orphans]
+//	    Print the dependency graph of the [-templates] directories and the [-public] pages, the pages using a template, or the orphaned templates.
+//
+//  lint [-errors-format text|json]
+//	    Check the [-templates] directories and the [-public] pages without executing them, exiting with a non-zero status on errors.
+//
+//  theme list | theme add <path> [name] | theme remove <name>
+//	    Manage the themes installed in the [-themes] directory from local directories or zip archives.
+//
 // [OPTIONS] are:
 //
+//  -cache string
+// 	    Sets the path for the processed assets cache. (default ".thtml-cache")
+//
+//  -config string
+// 	    Sets the path for the project configuration file. (default "thtml.json")
+//
+//  -compress string
+// 	    Provides a comma separated list of encodings (gzip, br) to precompress the build output and the dev server responses.
+//
+//  -compress-level int
+// 	    Sets the compression level. Uses the best compression of each encoding when <= 0.
+//
+//  -compress-min-size int
+// 	    Sets the minimum size in bytes of the files to compress. (default 1024)
+//
+//  -diff
+// 	    Like -dry-run, also showing unified diffs of the changed text files.
+//
+//  -drafts
+// 	    Include pages marked as draft in their front matter in the build output.
+//
+//  -dry-run
+// 	    Build in memory and list the files that would be added, changed or removed in the -output directory, without writing them.
+//
+//  -expired
+// 	    Include pages with an expiryDate in the past in the build output.
+//
+//  -errors-format string
+// 	    Sets the format of the build and lint errors: text or json. (default "text")
+//
 //  -exts string
 // 	    Provides a comma separated filename extensions list to support when parsing templates. (default ".thtml,.html,.css,.js")
 //
+//  -future
+// 	    Include pages with a publishDate in the future in the build output.
+//
+//  -keep-builds int
+// 	    Keeps the provided number of previous build outputs next to the -output directory for rollback.
+//
+//  -keep-going
+// 	    Build all files after errors, reporting the errors of all failed files at the end.
+//
 //  -listen string
 // 	    Run the dev server listening on the provided host:port. (default ":5500")
 //
@@ -31,8 +82,12 @@
 //  -public string
 // 	    Sets the path for the web root. (default "public")
 //
+//  -themes string
+// 	    Sets the path for the installed themes. (default "themes")
+//
 //  -templates string
-// 	    Sets the path for the template files. (default "templates")
+// 	    Sets the path for the template files.
+// 	    Accepts a comma separated list of directories, where templates in later directories override earlier ones. (default "templates")
 //
 //
 //
@@ -71,6 +126,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"os"
 )
 
@@ -79,31 +135,70 @@ const version = "1.1.0"
 // Configuration options
 var (
 	// Actions
-	_version bool
-	_build   bool
-	_run     bool
-	_init    bool
+	_version  bool
+	_build    bool
+	_rollback bool
+	_run      bool
+	_init     bool
 
 	// Configuration
+	_configPath    string
+	_config        = defaultConfig()
 	_publicPath    string
 	_templatesPath string
 	_outputPath    string
+	_keepBuilds    int
+	_cachePath     string
+	_themesPath    string
 	_exts          string
 	_minify        bool
 	_httpListen    string
+
+	// Dry run
+	_dryRun bool
+	_diff   bool
+
+	// Build errors
+	_keepGoing    bool
+	_errorsFormat string
+
+	// Publishing
+	_drafts  bool
+	_future  bool
+	_expired bool
+
+	// Precompression
+	_compress        string
+	_compressLevel   int
+	_compressMinSize int
 )
 
 func init() {
 	// Parse config flags
 	flag.BoolVar(&_version, "version", false, "Prints version number.")
 	flag.BoolVar(&_build, "build", false, "Build the assets from the -public directory to the -output directory by parsing the -templates directory.")
+	flag.BoolVar(&_rollback, "rollback", false, "Replaces the -output directory with its newest previous build kept by -keep-builds.")
 	flag.BoolVar(&_run, "run", false, "Run a dev web server serving the public directory.")
 	flag.BoolVar(&_init, "init", false, "Creates a new project structure into the current directory.")
 	flag.BoolVar(&_minify, "minify", true, "Minify the build output.")
+	flag.BoolVar(&_dryRun, "dry-run", false, "Build in memory and list the files that would be added, changed or removed in the -output directory, without writing them.")
+	flag.BoolVar(&_diff, "diff", false, "Like -dry-run, also showing unified diffs of the changed text files.")
+	flag.BoolVar(&_keepGoing, "keep-going", false, "Build all files after errors, reporting the errors of all failed files at the end.")
+	flag.StringVar(&_errorsFormat, "errors-format", "text", "Sets the format of the build and lint errors: text or json.")
+	flag.BoolVar(&_drafts, "drafts", false, "Include pages marked as draft in their front matter in the build output.")
+	flag.BoolVar(&_future, "future", false, "Include pages with a publishDate in the future in the build output.")
+	flag.BoolVar(&_expired, "expired", false, "Include pages with an expiryDate in the past in the build output.")
+	flag.StringVar(&_compress, "compress", "", "Provides a comma separated list of encodings (gzip, br) to precompress the build output and the dev server responses.")
+	flag.IntVar(&_compressLevel, "compress-level", 0, "Sets the compression level. Uses the best compression of each encoding when <= 0.")
+	flag.IntVar(&_compressMinSize, "compress-min-size", 1024, "Sets the minimum size in bytes of the files to compress.")
+	flag.StringVar(&_configPath, "config", "thtml.json", "Sets the path for the project configuration file.")
 	flag.StringVar(&_publicPath, "public", "public", "Sets the path for the web root.")
-	flag.StringVar(&_templatesPath, "templates", "templates", "Sets the path for the template files.")
+	flag.StringVar(&_templatesPath, "templates", "templates", "Sets the path for the template files. Accepts a comma separated list of directories, where templates in later directories override earlier ones.")
 	flag.StringVar(&_httpListen, "listen", "localhost:5500", "Run the dev server listening on the provided host:port.")
 	flag.StringVar(&_outputPath, "output", "build", "Sets the path for the build output.")
+	flag.IntVar(&_keepBuilds, "keep-builds", 0, "Keeps the provided number of previous build outputs next to the -output directory for rollback.")
+	flag.StringVar(&_cachePath, "cache", ".thtml-cache", "Sets the path for the processed assets cache.")
+	flag.StringVar(&_themesPath, "themes", "themes", "Sets the path for the installed themes.")
 	flag.StringVar(&_exts, "exts", ".html", "Provides a comma separated filename extensions list to support when parsing templates.")
 }
 
@@ -114,7 +209,10 @@ func printVersion() {
 func main() {
 	flag.Parse()
 
-	if !_run && !_build && !_init && !_version {
+	theme := flag.Arg(0) == "theme"
+	lintCmd := flag.Arg(0) == "lint"
+	graphCmd := flag.Arg(0) == "graph"
+	if !_run && !_build && !_rollback && !_init && !_version && !theme && !lintCmd && !graphCmd {
 		fmt.Println("")
 		fmt.Println("Run:")
 		fmt.Println("     ", os.Args[0], "-h")
@@ -124,6 +222,43 @@ func main() {
 		return
 	}
 
+	// Load config
+	var err error
+	_config, err = loadConfig(_configPath)
+	if err != nil {
+		if _build {
+			buildFailed(err, fmt.Sprintf("Error loading config file '%s'", _configPath))
+		}
+		log.Fatalf("Error loading config file '%s': %s", _configPath, err)
+	}
+
+	// Manage themes
+	if theme {
+		runTheme(flag.Args()[1:])
+		return
+	}
+	if _build || _run || lintCmd || graphCmd {
+		err = checkTheme()
+		if err != nil {
+			if _build {
+				buildFailed(err, "Error loading theme")
+			}
+			log.Fatalf("Error loading theme: %s", err)
+		}
+	}
+
+	// Lint
+	if lintCmd {
+		lint(flag.Args()[1:])
+		return
+	}
+
+	// Dependency graph
+	if graphCmd {
+		graph(flag.Args()[1:])
+		return
+	}
+
 	// Print version
 	if _version {
 		printVersion()
@@ -134,6 +269,11 @@ func main() {
 		create()
 	}
 
+	// Roll back if requested
+	if _rollback {
+		rollback()
+	}
+
 	// Build if requested
 	if _build {
 		build()
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+// slowestPages is the number of pages listed by the build report.
+const slowestPages = 5
+
+// printReport writes a summary of the build manifest: counts and sizes by media type,
+// the slowest pages to build and the build warnings.
+// Pages are the outputs with one of the template extensions.
+func printReport(w io.Writer, m *templates.Manifest, exts []string) {
+	type typeStats struct {
+		files, raw, size int
+	}
+
+	stats := make(map[string]*typeStats)
+	total := new(typeStats)
+	var pages []templates.ManifestEntry
+	for _, f := range m.Files {
+		mediaType := strings.TrimSpace(strings.Split(f.Type, ";")[0])
+		if stats[mediaType] == nil {
+			stats[mediaType] = new(typeStats)
+		}
+		for _, st := range []*typeStats{stats[mediaType], total} {
+			st.files++
+			st.raw += f.RawSize
+			st.size += f.Size
+		}
+
+		for _, ext := range exts {
+			if path.Ext(f.Path) == strings.TrimSpace(ext) {
+				pages = append(pages, f)
+				break
+			}
+		}
+	}
+
+	types := make([]string, 0, len(stats))
+	for t := range stats {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	// Outputs by type
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Type\tFiles\tUnminified\tOutput")
+	for _, t := range types {
+		st := stats[t]
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", t, st.files, formatBytes(st.raw), formatBytes(st.size))
+	}
+	fmt.Fprintf(tw, "Total\t%d\t%s\t%s\n", total.files, formatBytes(total.raw), formatBytes(total.size))
+	tw.Flush()
+
+	// Slowest pages
+	if len(pages) > 0 {
+		sort.SliceStable(pages, func(i, j int) bool {
+			return pages[i].Duration > pages[j].Duration
+		})
+		if len(pages) > slowestPages {
+			pages = pages[:slowestPages]
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Slowest pages:")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, p := range pages {
+			fmt.Fprintf(tw, "  %s\t%.1fms\n", p.Path, p.Duration)
+		}
+		tw.Flush()
+	}
+
+	// Warnings
+	if len(m.Warnings) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Warnings (%d):\n", len(m.Warnings))
+		for _, warning := range m.Warnings {
+			fmt.Fprintf(w, "  %s\n", warning)
+		}
+	}
+}
+
+// formatBytes returns a human readable size.
+func formatBytes(n int) string {
+	switch {
+	case n >= 1000*1000:
+		return fmt.Sprintf("%.1f MB", float64(n)/1000/1000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1f kB", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// printChanges lists the files added, changed or removed by a dry run build,
+// followed by their unified diffs when diff is true.
+func printChanges(w io.Writer, changes []templates.Change, diff bool) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, c := range changes {
+		counts[c.Status]++
+		fmt.Fprintf(w, "%s  %s\n", changeSymbols[c.Status], c.Path)
+	}
+	fmt.Fprintf(w, "\n%d added, %d changed, %d removed.\n",
+		counts[templates.ChangeAdded], counts[templates.ChangeChanged], counts[templates.ChangeRemoved])
+
+	if !diff {
+		return
+	}
+	for _, c := range changes {
+		fmt.Fprintln(w)
+		if !templates.IsText(c.Old) || !templates.IsText(c.New) {
+			fmt.Fprintf(w, "Binary files a/%s and b/%s differ\n", c.Path, c.Path)
+			continue
+		}
+		fmt.Fprint(w, templates.UnifiedDiff(c.Path, c.Old, c.New))
+	}
+}
+
+// changeSymbols are the short status of the changes listed by printChanges.
+var changeSymbols = map[string]string{
+	templates.ChangeAdded:   "A",
+	templates.ChangeChanged: "M",
+	templates.ChangeRemoved: "D",
+}
+
+// printErrors writes the errors of a failed build grouped by the file where they happened, in text or json format.
+func printErrors(w io.Writer, err error, format string) error {
+	var errs []templates.BuildError
+	if be, ok := err.(templates.BuildErrors); ok {
+		errs = append([]templates.BuildError(nil), be.Errors...)
+	} else {
+		errs = []templates.BuildError{templates.NewBuildError("", err)}
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(errs)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].File < errs[j].File
+	})
+	for i, e := range errs {
+		if i == 0 || e.File != errs[i-1].File {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s:\n", e.File)
+		}
+
+		loc := ""
+		if e.Line > 0 {
+			loc = strconv.Itoa(e.Line) + ": "
+			if e.Column > 0 {
+				loc = strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Column) + ": "
+			}
+		}
+		page := ""
+		if e.Page != "" && e.Page != e.File {
+			page = " (building " + e.Page + ")"
+		}
+		fmt.Fprintf(w, "  %s%s%s\n", loc, e.Message, page)
+	}
+	fmt.Fprintf(w, "\n%d errors.\n", len(errs))
+
+	return nil
+}
+
+// printLint writes the issues found by the lint command in text or json format,
+// and returns the number of errors among them.
+func printLint(w io.Writer, issues []templates.LintIssue, format string) int {
+	errs := 0
+	for _, issue := range issues {
+		if issue.Severity == templates.LintError {
+			errs++
+		}
+	}
+
+	if format == "json" {
+		if issues == nil {
+			issues = []templates.LintIssue{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		enc.Encode(issues)
+		return errs
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintln(w, issue)
+	}
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No issues.")
+	} else {
+		fmt.Fprintf(w, "\n%d errors, %d warnings.\n", errs, len(issues)-errs)
+	}
+
+	return errs
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+func TestPrintReport(t *testing.T) {
+	m := &templates.Manifest{
+		Files: []templates.ManifestEntry{
+			{Path: "index.html", Type: "text/html; charset=utf-8", Size: 900, RawSize: 1500, Duration: 1.5},
+			{Path: "about.html", Type: "text/html; charset=utf-8", Size: 100, RawSize: 100, Duration: 4},
+			{Path: "css/site.css", Type: "text/css; charset=utf-8", Size: 2000000, RawSize: 2500000, Duration: 9},
+		},
+		Warnings: []string{"Skipped draft page draft.html"},
+	}
+
+	buff := new(bytes.Buffer)
+	printReport(buff, m, []string{".html"})
+	out := buff.String()
+
+	for _, expected := range []string{
+		"text/css   1      2.5 MB      2.0 MB",
+		"text/html  2      1.6 kB      1.0 kB",
+		"Total      3      2.5 MB      2.0 MB",
+		"Slowest pages:\n  about.html  4.0ms\n  index.html  1.5ms\n",
+		"Warnings (1):\n  Skipped draft page draft.html\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected '%s' in report. Got:\n%s", expected, out)
+		}
+	}
+}
+
+func TestPrintChanges(t *testing.T) {
+	changes := []templates.Change{
+		{Path: "about.html", Status: templates.ChangeChanged, Old: []byte("About\n"), New: []byte("About us\n")},
+		{Path: "img/logo.png", Status: templates.ChangeAdded, New: []byte("\x89PNG\x00")},
+		{Path: "old.html", Status: templates.ChangeRemoved, Old: []byte("Old\n")},
+	}
+
+	buff := new(bytes.Buffer)
+	printChanges(buff, changes, true)
+
+	expected := `M  about.html
+A  img/logo.png
+D  old.html
+
+1 added, 1 changed, 1 removed.
+
+--- a/about.html
++++ b/about.html
+@@ -1,1 +1,1 @@
+-About
++About us
+
+Binary files a/img/logo.png and b/img/logo.png differ
+
+--- a/old.html
++++ b/old.html
+@@ -1,1 +0,0 @@
+-Old
+`
+	if buff.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buff.String())
+	}
+}
+
+func TestPrintErrors(t *testing.T) {
+	err := templates.NewBuildErrors([]templates.BuildError{
+		templates.NewBuildError("index.html", errors.New(`template: layouts/default.html:2:14: executing "layouts/default.html" at <index . 1>: error calling index`)),
+		templates.NewBuildError("broken.html", errors.New("template: /broken.html:3: missing value for if")),
+		templates.NewBuildError("about.html", errors.New(`template: layouts/default.html:2:14: executing "layouts/default.html" at <index . 1>: error calling index`)),
+	})
+
+	buff := new(bytes.Buffer)
+	printErrors(buff, err, "text")
+	expected := "broken.html:\n" +
+		"  3: template: /broken.html:3: missing value for if\n" +
+		"\n" +
+		"layouts/default.html:\n" +
+		"  2:14: template: layouts/default.html:2:14: executing \"layouts/default.html\" at <index . 1>: error calling index (building index.html)\n" +
+		"  2:14: template: layouts/default.html:2:14: executing \"layouts/default.html\" at <index . 1>: error calling index (building about.html)\n" +
+		"\n" +
+		"3 errors.\n"
+	if buff.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buff.String())
+	}
+
+	// JSON
+	buff.Reset()
+	printErrors(buff, err, "json")
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buff.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0]["file"] != "layouts/default.html" || got[0]["page"] != "index.html" || got[0]["line"] != 2.0 || got[0]["column"] != 14.0 {
+		t.Errorf("Unexpected JSON errors %v", got)
+	}
+
+	// Single error
+	buff.Reset()
+	printErrors(buff, errors.New("Error reading template broken.html: permission denied"), "json")
+	if !strings.Contains(buff.String(), `"message": "Error reading template broken.html: permission denied"`) {
+		t.Errorf("Unexpected JSON error %s", buff.String())
+	}
+}
+
+func TestPrintLint(t *testing.T) {
+	issues := []templates.LintIssue{
+		{File: "layouts/default.html", Line: 12, Severity: templates.LintError, Message: `template "footer.html" is not defined`},
+		{File: "partials/old.html", Severity: templates.LintWarning, Message: "template is never referenced"},
+	}
+
+	buff := new(bytes.Buffer)
+	errs := printLint(buff, issues, "text")
+	expected := "layouts/default.html:12: error: template \"footer.html\" is not defined\n" +
+		"partials/old.html: warning: template is never referenced\n" +
+		"\n" +
+		"1 errors, 1 warnings.\n"
+	if errs != 1 || buff.String() != expected {
+		t.Errorf("Expected 1 error and:\n%s\nGot %d and:\n%s", expected, errs, buff.String())
+	}
+
+	buff.Reset()
+	errs = printLint(buff, nil, "json")
+	if errs != 0 || buff.String() != "[]\n" {
+		t.Errorf("Expected no errors and '[]'. Got %d and '%s'", errs, buff.String())
+	}
+}
@@ -0,0 +1,300 @@
+package scss
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Variable references, optionally namespaced by a module: $name or module.$name
+	varRef = regexp.MustCompile(`(?:([a-zA-Z_][\w-]*)\.)?\$([a-zA-Z_][\w-]*)`)
+
+	// Interpolations: #{expression}
+	interpolation = regexp.MustCompile(`#\{([^}]*)\}`)
+
+	// Parenthesized groups not preceded by a function name
+	group = regexp.MustCompile(`(^|[^\w-])\(([^()]*)\)`)
+
+	// Numbers with optional unit
+	number = regexp.MustCompile(`^(-?[0-9]*\.?[0-9]+)([a-zA-Z%]*)$`)
+
+	// Function calls, with optional vendor prefix
+	function = regexp.MustCompile(`(-?[a-zA-Z_][\w-]*)\(`)
+
+	// Operations between numbers without spaces, i.e. "10px*2"
+	unspaced = regexp.MustCompile(`^(-?[0-9]*\.?[0-9]+)([a-zA-Z%]*)[*+-](-?[0-9]*\.?[0-9]+)[a-zA-Z%]*$`)
+
+	// Quoted strings and url() contents, left out when checking values
+	literal = regexp.MustCompile(`"[^"]*"|'[^']*'|(?i:url)\([^)]*\)`)
+)
+
+// cssFunctions are the plain CSS functions kept in values. Any other function is a Sass function, which isn't supported.
+var cssFunctions = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		calc min max clamp var env attr url rgb rgba hsl hsla hwb lab lch oklab oklch color color-mix light-dark
+		linear-gradient radial-gradient conic-gradient repeating-linear-gradient repeating-radial-gradient repeating-conic-gradient
+		image image-set cross-fade element paint
+		translate translatex translatey translatez translate3d scale scalex scaley scalez scale3d
+		rotate rotatex rotatey rotatez rotate3d skew skewx skewy matrix matrix3d perspective
+		blur brightness contrast drop-shadow grayscale hue-rotate invert opacity saturate sepia
+		cubic-bezier steps linear repeat minmax fit-content counter counters symbols format local tech
+		circle ellipse inset polygon path rect xywh ray round mod rem sin cos tan asin acos atan atan2 pow sqrt hypot log exp abs sign
+		anchor anchor-size leader target-counter target-counters target-text string content running selector layer supports`) {
+		cssFunctions[name] = true
+	}
+}
+
+// scope holds the variables, mixins and modules visible from a block.
+type scope struct {
+	parent  *scope
+	vars    map[string]string
+	mixins  map[string]*mixin
+	modules map[string]*scope
+
+	// Content block passed to the mixin being included
+	content *content
+}
+
+// mixin is a @mixin definition.
+type mixin struct {
+	params []param
+	body   []*node
+	scope  *scope
+	src    source
+}
+
+// param is a mixin parameter with its optional default value.
+type param struct {
+	name  string
+	value string
+}
+
+// content is the block passed to a mixin with @include, rendered by @content.
+type content struct {
+	nodes []*node
+	scope *scope
+	src   source
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:  parent,
+		vars:    make(map[string]string),
+		mixins:  make(map[string]*mixin),
+		modules: make(map[string]*scope),
+	}
+}
+
+// root returns the outermost scope.
+func (s *scope) root() *scope {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// variable returns the value of the variable, looking up outer scopes.
+func (s *scope) variable(name string) (string, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if v, ok := sc.vars[name]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// mixin returns the named mixin, looking up outer scopes.
+func (s *scope) mixin(name string) (*mixin, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if m, ok := sc.mixins[name]; ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// module returns the scope of the module loaded with @use under the namespace.
+func (s *scope) module(ns string) (*scope, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if m, ok := sc.modules[ns]; ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// currentContent returns the @content block of the innermost mixin being included.
+func (s *scope) currentContent() *content {
+	for sc := s; sc != nil; sc = sc.parent {
+		if sc.content != nil {
+			return sc.content
+		}
+	}
+	return nil
+}
+
+// eval resolves interpolations, variables and arithmetic in a value.
+func (s *scope) eval(value string) (string, error) {
+	var err error
+
+	// Interpolations
+	value = interpolation.ReplaceAllStringFunc(value, func(m string) string {
+		v, e := s.eval(interpolation.FindStringSubmatch(m)[1])
+		if e != nil {
+			err = e
+		}
+		return unquote(v)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Variables
+	value = varRef.ReplaceAllStringFunc(value, func(m string) string {
+		parts := varRef.FindStringSubmatch(m)
+		sc := s
+		if parts[1] != "" {
+			mod, ok := s.module(parts[1])
+			if !ok {
+				// Not a namespace, i.e. a property value like "a.$b" isn't valid anyway
+				err = errorf("Undefined module %s", parts[1])
+				return m
+			}
+			sc = mod
+		}
+
+		v, ok := sc.variable(parts[2])
+		if !ok {
+			err = errorf("Undefined variable $%s", parts[2])
+			return m
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Arithmetic
+	if strings.Contains(value, "calc(") {
+		return value, nil
+	}
+	for {
+		reduced := group.ReplaceAllStringFunc(value, func(m string) string {
+			parts := group.FindStringSubmatch(m)
+			if r, ok := arithmetic(parts[2]); ok && !strings.ContainsAny(r, " ,") {
+				return parts[1] + r
+			}
+			return m
+		})
+		if reduced == value {
+			break
+		}
+		value = reduced
+	}
+	if r, ok := arithmetic(value); ok {
+		value = r
+	}
+
+	return value, nil
+}
+
+// checkValue returns an error for the parts of an evaluated value that aren't plain CSS:
+// calls to Sass functions and operations between numbers that couldn't be reduced.
+func checkValue(value string) error {
+	v := literal.ReplaceAllString(value, "")
+
+	for _, m := range function.FindAllStringSubmatch(v, -1) {
+		name := strings.ToLower(m[1])
+		if strings.HasPrefix(name, "-") {
+			// Vendor prefixed, i.e. -webkit-gradient()
+			continue
+		}
+		if !cssFunctions[name] {
+			return errorf("Function %s() is not supported", m[1])
+		}
+	}
+
+	if strings.Contains(v, "calc(") {
+		return nil
+	}
+	fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ", ",", " ").Replace(v))
+	for i, f := range fields {
+		if m := unspaced.FindStringSubmatch(f); m != nil && m[2] != "e" && m[2] != "E" {
+			return errorf("Operation %s needs spaces around the operator", f)
+		}
+		if i > 0 && i+1 < len(fields) && len(f) == 1 && strings.Contains("*+-", f) &&
+			number.MatchString(fields[i-1]) && number.MatchString(fields[i+1]) {
+			return errorf("Incompatible units in %s %s %s", fields[i-1], f, fields[i+1])
+		}
+	}
+
+	return nil
+}
+
+// arithmetic reduces "*", "+" and "-" operations between numbers separated by spaces, i.e. "10px * 2 + 4px".
+// Division isn't supported, as "/" is a separator in plain CSS values.
+func arithmetic(expr string) (string, bool) {
+	fields := strings.Fields(expr)
+	changed := false
+
+	for _, ops := range []string{"*", "+-"} {
+		for i := 1; i+1 < len(fields); {
+			op := fields[i]
+			if len(op) != 1 || !strings.Contains(ops, op) {
+				i++
+				continue
+			}
+			r, ok := operate(fields[i-1], op, fields[i+1])
+			if !ok {
+				i++
+				continue
+			}
+			fields = append(fields[:i-1], append([]string{r}, fields[i+2:]...)...)
+			changed = true
+		}
+	}
+
+	return strings.Join(fields, " "), changed
+}
+
+// operate computes a single operation between two numbers with compatible units.
+func operate(a, op, b string) (string, bool) {
+	ma := number.FindStringSubmatch(a)
+	mb := number.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return "", false
+	}
+	x, _ := strconv.ParseFloat(ma[1], 64)
+	y, _ := strconv.ParseFloat(mb[1], 64)
+
+	unit := ma[2]
+	if unit == "" {
+		unit = mb[2]
+	} else if mb[2] != "" && (mb[2] != unit || op == "*") {
+		return "", false
+	}
+
+	var r float64
+	switch op {
+	case "*":
+		r = x * y
+	case "+":
+		r = x + y
+	case "-":
+		r = x - y
+	}
+
+	return strconv.FormatFloat(math.Round(r*1e5)/1e5, 'f', -1, 64) + unit, true
+}
+
+// unquote removes the quotes of a string value.
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,172 @@
+package scss
+
+import (
+	"strings"
+)
+
+// node is a statement of a stylesheet: a variable, a declaration, a rule or an at-rule,
+// with its child statements when it has a block.
+type node struct {
+	text     string
+	line     int
+	block    bool
+	children []*node
+}
+
+// parse splits the stylesheet source into a tree of statements.
+// Comments are dropped, strings and interpolations are kept as they are.
+func parse(file, src string) ([]*node, error) {
+	root := &node{block: true}
+	stack := []*node{root}
+
+	buff := new(strings.Builder)
+	line, start, parens := 1, 0, 0
+
+	// Statement start tracking
+	mark := func() {
+		if start == 0 {
+			start = line
+		}
+	}
+	add := func(block bool) *node {
+		n := &node{text: strings.TrimSpace(buff.String()), line: start, block: block}
+		buff.Reset()
+		start = 0
+		if n.text == "" && !block {
+			return nil
+		}
+
+		top := stack[len(stack)-1]
+		top.children = append(top.children, n)
+		return n
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		next := byte(0)
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+
+		switch {
+		case c == '\n':
+			line++
+			buff.WriteByte(c)
+
+		case c == '"' || c == '\'':
+			mark()
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' {
+					j++
+				}
+				if j < len(src) && src[j] == '\n' {
+					return nil, newError(file, line, "Unterminated string")
+				}
+			}
+			if j >= len(src) {
+				return nil, newError(file, line, "Unterminated string")
+			}
+			buff.WriteString(src[i : j+1])
+			i = j
+
+		case c == '/' && next == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, newError(file, line, "Unterminated comment")
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 3
+
+		case c == '/' && next == '/' && parens == 0:
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end - 1
+			}
+
+		case c == '#' && next == '{':
+			mark()
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, newError(file, line, "Unterminated interpolation")
+			}
+			buff.WriteString(src[i : i+end+1])
+			i += end
+
+		case c == '(':
+			mark()
+			parens++
+			buff.WriteByte(c)
+
+		case c == ')':
+			parens--
+			buff.WriteByte(c)
+
+		case c == '{' && parens <= 0:
+			if start == 0 {
+				return nil, newError(file, line, "Expected selector before '{'")
+			}
+			n := add(true)
+			stack = append(stack, n)
+			parens = 0
+
+		case c == ';' && parens <= 0:
+			add(false)
+			parens = 0
+
+		case c == '}' && parens <= 0:
+			add(false)
+			if len(stack) == 1 {
+				return nil, newError(file, line, "Unexpected '}'")
+			}
+			stack = stack[:len(stack)-1]
+			parens = 0
+
+		default:
+			if c != ' ' && c != '\t' && c != '\r' {
+				mark()
+			}
+			buff.WriteByte(c)
+		}
+	}
+
+	add(false)
+	if len(stack) > 1 {
+		top := stack[len(stack)-1]
+		return nil, newError(file, top.line, "Unclosed block '"+top.text+"'")
+	}
+
+	return root.children, nil
+}
+
+// splitTop splits s by sep, ignoring separators inside parentheses and strings.
+func splitTop(s string, sep byte) []string {
+	var parts []string
+	depth, last := 0, 0
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[last:i]))
+			last = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(s[last:]))
+}
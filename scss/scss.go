@@ -0,0 +1,632 @@
+// Package scss implements a minimal SCSS compiler in pure Go, so stylesheets can be compiled without an external sass binary.
+//
+// Supported features:
+//
+//  - Variables, with !default and !global flags, and #{} interpolation in selectors, properties and values.
+//  - Nested rules, the & parent selector and @media/@supports bubbling.
+//  - @import and @use of partials ("_name.scss"), resolved relative to the importing file and then to the include paths.
+//    Modules loaded with @use are accessed through their namespace (namespace.$var, @include namespace.mixin),
+//    or without namespace when loaded with "as *".
+//  - @mixin and @include, with default and keyword arguments and @content blocks.
+//  - "*", "+" and "-" arithmetic between numbers with compatible units.
+//
+// Control flow (@if, @each, @for, @while), @function, @extend, maps, built-in modules and Sass functions are not supported,
+// and return an error pointing to the file and line using them, as well as operations between numbers that can't be reduced.
+//
+// Example
+//
+//  css, err := scss.Compile(os.DirFS("public"), "css/site.scss", os.DirFS("scss"))
+//
+package scss
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Error is a compilation error located in a stylesheet.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.File == "" {
+		return e.Msg
+	}
+	return e.File + ":" + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+func newError(file string, line int, msg string) *Error {
+	return &Error{File: file, Line: line, Msg: msg}
+}
+
+func errorf(format string, v ...interface{}) *Error {
+	return &Error{Msg: fmt.Sprintf(format, v...)}
+}
+
+// IsPartial returns true for stylesheets only meant to be imported, named with a leading underscore.
+func IsPartial(name string) bool {
+	return strings.HasPrefix(path.Base(name), "_")
+}
+
+// Compile compiles the named SCSS stylesheet of fsys into CSS.
+// Imported stylesheets are resolved relative to the importing file, and then to each of the include file systems.
+func Compile(fsys fs.FS, name string, includes ...fs.FS) ([]byte, error) {
+	c := &compiler{
+		roots:   append([]fs.FS{fsys}, includes...),
+		modules: make(map[source]*scope),
+		loading: make(map[source]bool),
+	}
+
+	sc := newScope(nil)
+	err := c.load(source{root: 0, name: name}, context{scope: sc})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.css(), nil
+}
+
+// source is a stylesheet in one of the compiler file systems.
+type source struct {
+	root int
+	name string
+}
+
+// wrapper is an at-rule wrapping output rules. Only @media and @supports blocks with the same prelude are merged.
+type wrapper struct {
+	text string
+	id   int
+}
+
+// rule is a block of the output.
+type rule struct {
+	wrap     []wrapper
+	selector string
+	decls    []string
+	raw      string
+}
+
+// context is the state of the block being compiled.
+type context struct {
+	src       source
+	scope     *scope
+	selectors []string
+	wrap      []wrapper
+	rule      *rule
+}
+
+type compiler struct {
+	roots   []fs.FS
+	modules map[source]*scope
+	loading map[source]bool
+	out     []*rule
+	ids     int
+
+	// Plain CSS imports, written before all rules as CSS requires
+	imports []string
+}
+
+// load parses and compiles a stylesheet in the provided context.
+func (c *compiler) load(src source, ctx context) error {
+	if c.loading[src] {
+		return errorf("Import loop in %s", src.name)
+	}
+	c.loading[src] = true
+	defer delete(c.loading, src)
+
+	content, err := fs.ReadFile(c.roots[src.root], src.name)
+	if err != nil {
+		return errorf("Error reading %s: %s", src.name, err)
+	}
+	nodes, err := parse(src.name, string(content))
+	if err != nil {
+		return err
+	}
+
+	ctx.src = src
+	return c.compile(nodes, ctx)
+}
+
+// resolve finds the stylesheet imported with url from the src stylesheet.
+func (c *compiler) resolve(src source, url string) (source, bool) {
+	candidates := func(p string) []string {
+		dir, base := path.Dir(p), path.Base(p)
+		if strings.HasSuffix(base, ".scss") {
+			return []string{p, path.Join(dir, "_"+base)}
+		}
+		return []string{
+			p + ".scss",
+			path.Join(dir, "_"+base+".scss"),
+			path.Join(p, "_index.scss"),
+			path.Join(p, "index.scss"),
+		}
+	}
+
+	// Relative to the importing file
+	for _, name := range candidates(path.Join(path.Dir(src.name), url)) {
+		if info, err := fs.Stat(c.roots[src.root], name); err == nil && !info.IsDir() {
+			return source{root: src.root, name: name}, true
+		}
+	}
+
+	// Include paths
+	for i := 1; i < len(c.roots); i++ {
+		for _, name := range candidates(path.Clean(url)) {
+			if info, err := fs.Stat(c.roots[i], name); err == nil && !info.IsDir() {
+				return source{root: i, name: name}, true
+			}
+		}
+	}
+
+	return source{}, false
+}
+
+// at locates an error at the node, unless it's already located.
+func (c *compiler) at(ctx context, n *node, err error) error {
+	if e, ok := err.(*Error); ok && e.File == "" {
+		e.File, e.Line = ctx.src.name, n.line
+		return e
+	}
+	return err
+}
+
+// newRule appends an output rule.
+func (c *compiler) newRule(ctx context, selectors []string) *rule {
+	r := &rule{
+		wrap:     ctx.wrap,
+		selector: strings.Join(selectors, ", "),
+	}
+	c.out = append(c.out, r)
+	return r
+}
+
+// compile compiles a list of statements.
+func (c *compiler) compile(nodes []*node, ctx context) error {
+	for _, n := range nodes {
+		var err error
+		switch {
+		case strings.HasPrefix(n.text, "@"):
+			err = c.atRule(n, ctx)
+
+		case n.block:
+			err = c.nestedRule(n, ctx)
+
+		case strings.HasPrefix(n.text, "$"):
+			err = c.variable(n, ctx)
+
+		default:
+			err = c.declaration(n, ctx)
+		}
+		if err != nil {
+			return c.at(ctx, n, err)
+		}
+	}
+
+	return nil
+}
+
+// variable assigns a variable: $name: value [!default] [!global]
+func (c *compiler) variable(n *node, ctx context) error {
+	kv := strings.SplitN(n.text, ":", 2)
+	if len(kv) != 2 {
+		return errorf("Expected ':' in variable declaration")
+	}
+	name := strings.TrimSpace(kv[0][1:])
+	value := strings.TrimSpace(kv[1])
+
+	sc := ctx.scope
+	def := false
+	for {
+		if strings.HasSuffix(value, "!default") {
+			value, def = strings.TrimSpace(strings.TrimSuffix(value, "!default")), true
+		} else if strings.HasSuffix(value, "!global") {
+			value, sc = strings.TrimSpace(strings.TrimSuffix(value, "!global")), sc.root()
+		} else {
+			break
+		}
+	}
+	if _, ok := sc.variable(name); def && ok {
+		return nil
+	}
+
+	v, err := ctx.scope.eval(value)
+	if err != nil {
+		return err
+	}
+	sc.vars[name] = v
+
+	return nil
+}
+
+// declaration adds a property to the current rule: property: value
+func (c *compiler) declaration(n *node, ctx context) error {
+	kv := strings.SplitN(n.text, ":", 2)
+	if len(kv) != 2 {
+		return errorf("Expected ':' in declaration '%s'", n.text)
+	}
+	if ctx.rule == nil {
+		return errorf("Declarations must be inside a rule")
+	}
+
+	prop, err := ctx.scope.eval(strings.TrimSpace(kv[0]))
+	if err != nil {
+		return err
+	}
+	value, err := ctx.scope.eval(strings.TrimSpace(kv[1]))
+	if err != nil {
+		return err
+	}
+	err = checkValue(value)
+	if err != nil {
+		return err
+	}
+	ctx.rule.decls = append(ctx.rule.decls, prop+": "+value)
+
+	return nil
+}
+
+// nestedRule compiles a style rule, nesting its selectors into the parent ones.
+func (c *compiler) nestedRule(n *node, ctx context) error {
+	sel, err := ctx.scope.eval(n.text)
+	if err != nil {
+		return err
+	}
+
+	var selectors []string
+	for _, child := range splitTop(sel, ',') {
+		if len(ctx.selectors) == 0 {
+			if strings.Contains(child, "&") {
+				return errorf("Parent selector '&' used at the top level")
+			}
+			selectors = append(selectors, child)
+			continue
+		}
+		for _, parent := range ctx.selectors {
+			if strings.Contains(child, "&") {
+				selectors = append(selectors, strings.Replace(child, "&", parent, -1))
+			} else {
+				selectors = append(selectors, parent+" "+child)
+			}
+		}
+	}
+
+	ctx.selectors = selectors
+	ctx.rule = c.newRule(ctx, selectors)
+	ctx.scope = newScope(ctx.scope)
+
+	return c.compile(n.children, ctx)
+}
+
+// atRule compiles an at-rule.
+func (c *compiler) atRule(n *node, ctx context) error {
+	name := n.text
+	params := ""
+	if i := strings.IndexAny(n.text, " \t\n("); i > 0 {
+		name, params = n.text[:i], strings.TrimSpace(n.text[i:])
+	}
+
+	switch name {
+	case "@import":
+		if n.block {
+			return errorf("Unexpected block after @import")
+		}
+		return c.importRule(params, ctx)
+
+	case "@use":
+		if n.block {
+			return errorf("Unexpected block after @use")
+		}
+		return c.useRule(params, ctx)
+
+	case "@mixin":
+		if !n.block {
+			return errorf("Expected block after @mixin")
+		}
+		return c.mixinRule(params, n, ctx)
+
+	case "@include":
+		var body []*node
+		if n.block {
+			body = n.children
+		}
+		return c.includeRule(params, body, ctx)
+
+	case "@content":
+		cnt := ctx.scope.currentContent()
+		if cnt == nil {
+			return nil
+		}
+		cctx := ctx
+		cctx.scope = newScope(cnt.scope)
+		cctx.src = cnt.src
+		return c.compile(cnt.nodes, cctx)
+
+	case "@media", "@supports":
+		if !n.block {
+			return errorf("Expected block after %s", name)
+		}
+		prelude, err := ctx.scope.eval(params)
+		if err != nil {
+			return err
+		}
+		ctx.wrap = append(append([]wrapper{}, ctx.wrap...), wrapper{text: name + " " + prelude})
+		ctx.scope = newScope(ctx.scope)
+		ctx.rule = nil
+		if len(ctx.selectors) > 0 {
+			ctx.rule = c.newRule(ctx, ctx.selectors)
+		}
+		return c.compile(n.children, ctx)
+
+	case "@charset":
+		return nil
+
+	case "@error":
+		msg, err := ctx.scope.eval(params)
+		if err != nil {
+			return err
+		}
+		return errorf("%s", unquote(msg))
+
+	case "@warn", "@debug":
+		return nil
+
+	case "@if", "@else", "@each", "@for", "@while", "@function", "@return", "@extend", "@forward", "@at-root":
+		return errorf("%s is not supported", name)
+	}
+
+	prelude, err := ctx.scope.eval(n.text)
+	if err != nil {
+		return err
+	}
+
+	// Plain CSS statements, i.e. @namespace
+	if !n.block {
+		c.out = append(c.out, &rule{raw: prelude + ";"})
+		return nil
+	}
+
+	// Plain CSS blocks, i.e. @font-face or @keyframes
+	c.ids++
+	ctx.wrap = append(append([]wrapper{}, ctx.wrap...), wrapper{text: prelude, id: c.ids})
+	ctx.selectors = nil
+	ctx.scope = newScope(ctx.scope)
+	ctx.rule = c.newRule(ctx, nil)
+
+	return c.compile(n.children, ctx)
+}
+
+// importRule inlines the imported stylesheets in the current context.
+// Plain CSS imports are kept at the start of the output, as CSS ignores them after other rules.
+func (c *compiler) importRule(params string, ctx context) error {
+	for _, url := range splitTop(params, ',') {
+		u := unquote(url)
+		if u == url || strings.HasSuffix(u, ".css") || strings.Contains(u, "://") || strings.HasPrefix(u, "//") {
+			c.imports = append(c.imports, "@import "+url+";")
+			continue
+		}
+
+		src, ok := c.resolve(ctx.src, u)
+		if !ok {
+			return errorf("Can't find stylesheet to import: %s", u)
+		}
+		err := c.load(src, ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// useRule loads a module once and makes its members available under a namespace.
+func (c *compiler) useRule(params string, ctx context) error {
+	parts := strings.Fields(params)
+	if len(parts) == 0 {
+		return errorf("Expected a stylesheet URL in @use")
+	}
+	url := unquote(parts[0])
+	if strings.HasPrefix(url, "sass:") {
+		return errorf("Built-in module %s is not supported", url)
+	}
+
+	ns := strings.TrimPrefix(path.Base(url), "_")
+	ns = strings.TrimSuffix(ns, ".scss")
+	if len(parts) == 3 && parts[1] == "as" {
+		ns = parts[2]
+	} else if len(parts) != 1 {
+		return errorf("Unsupported @use parameters: %s", params)
+	}
+
+	src, ok := c.resolve(ctx.src, url)
+	if !ok {
+		return errorf("Can't find stylesheet to use: %s", url)
+	}
+
+	// Load once, at the top level
+	mod, ok := c.modules[src]
+	if !ok {
+		mod = newScope(nil)
+		err := c.load(src, context{scope: mod})
+		if err != nil {
+			return err
+		}
+		c.modules[src] = mod
+	}
+
+	if ns == "*" {
+		for k, v := range mod.vars {
+			ctx.scope.vars[k] = v
+		}
+		for k, m := range mod.mixins {
+			ctx.scope.mixins[k] = m
+		}
+		return nil
+	}
+	ctx.scope.modules[ns] = mod
+
+	return nil
+}
+
+// mixinRule defines a mixin: @mixin name($param, $param: default) { ... }
+func (c *compiler) mixinRule(params string, n *node, ctx context) error {
+	name, args := params, ""
+	if i := strings.IndexByte(params, '('); i >= 0 {
+		if !strings.HasSuffix(params, ")") {
+			return errorf("Expected ')' in @mixin")
+		}
+		name, args = strings.TrimSpace(params[:i]), params[i+1:len(params)-1]
+	}
+
+	m := &mixin{
+		body:  n.children,
+		scope: ctx.scope,
+		src:   ctx.src,
+	}
+	if strings.TrimSpace(args) != "" {
+		for _, arg := range splitTop(args, ',') {
+			kv := strings.SplitN(arg, ":", 2)
+			p := param{name: strings.TrimPrefix(strings.TrimSpace(kv[0]), "$")}
+			if len(kv) == 2 {
+				p.value = strings.TrimSpace(kv[1])
+			}
+			m.params = append(m.params, p)
+		}
+	}
+	ctx.scope.mixins[name] = m
+
+	return nil
+}
+
+// includeRule renders a mixin in the current context: @include name(args) { content }
+func (c *compiler) includeRule(params string, body []*node, ctx context) error {
+	name, args := params, ""
+	if i := strings.IndexByte(params, '('); i >= 0 {
+		if !strings.HasSuffix(params, ")") {
+			return errorf("Expected ')' in @include")
+		}
+		name, args = strings.TrimSpace(params[:i]), params[i+1:len(params)-1]
+	}
+
+	// Find mixin
+	var m *mixin
+	var ok bool
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		mod, found := ctx.scope.module(name[:i])
+		if !found {
+			return errorf("Undefined module %s", name[:i])
+		}
+		m, ok = mod.mixins[name[i+1:]]
+	} else {
+		m, ok = ctx.scope.mixin(name)
+	}
+	if !ok {
+		return errorf("Undefined mixin %s", name)
+	}
+
+	// Bind arguments, evaluated in the caller scope
+	sc := newScope(m.scope)
+	if strings.TrimSpace(args) != "" {
+		for i, arg := range splitTop(args, ',') {
+			if strings.HasPrefix(arg, "$") && strings.Contains(arg, ":") {
+				kv := strings.SplitN(arg, ":", 2)
+				v, err := ctx.scope.eval(strings.TrimSpace(kv[1]))
+				if err != nil {
+					return err
+				}
+				sc.vars[strings.TrimSpace(kv[0][1:])] = v
+				continue
+			}
+			if i >= len(m.params) {
+				return errorf("Too many arguments for mixin %s", name)
+			}
+			v, err := ctx.scope.eval(arg)
+			if err != nil {
+				return err
+			}
+			sc.vars[m.params[i].name] = v
+		}
+	}
+	for _, p := range m.params {
+		if _, ok := sc.vars[p.name]; ok {
+			continue
+		}
+		if p.value == "" {
+			return errorf("Missing argument $%s for mixin %s", p.name, name)
+		}
+		v, err := sc.eval(p.value)
+		if err != nil {
+			return err
+		}
+		sc.vars[p.name] = v
+	}
+	if body != nil {
+		sc.content = &content{nodes: body, scope: ctx.scope, src: ctx.src}
+	}
+
+	ctx.scope = sc
+	ctx.src = m.src
+
+	return c.compile(m.body, ctx)
+}
+
+// css writes the plain CSS imports and the output rules, merging consecutive rules in the same at-rule blocks.
+func (c *compiler) css() []byte {
+	b := new(strings.Builder)
+	var open []wrapper
+
+	for _, imp := range c.imports {
+		b.WriteString(imp + "\n")
+	}
+
+	closeTo := func(n int) {
+		for len(open) > n {
+			open = open[:len(open)-1]
+			b.WriteString(strings.Repeat("  ", len(open)) + "}\n")
+		}
+	}
+
+	for _, r := range c.out {
+		if r.raw != "" {
+			closeTo(0)
+			b.WriteString(r.raw + "\n")
+			continue
+		}
+		if len(r.decls) == 0 {
+			continue
+		}
+
+		// Open the rule wrappers
+		k := 0
+		for k < len(open) && k < len(r.wrap) && open[k] == r.wrap[k] {
+			k++
+		}
+		closeTo(k)
+		for _, w := range r.wrap[k:] {
+			b.WriteString(strings.Repeat("  ", len(open)) + w.text + " {\n")
+			open = append(open, w)
+		}
+
+		indent := strings.Repeat("  ", len(open))
+		if r.selector == "" {
+			for _, d := range r.decls {
+				b.WriteString(indent + d + ";\n")
+			}
+			continue
+		}
+		b.WriteString(indent + r.selector + " {\n")
+		for _, d := range r.decls {
+			b.WriteString(indent + "  " + d + ";\n")
+		}
+		b.WriteString(indent + "}\n")
+	}
+	closeTo(0)
+
+	return []byte(b.String())
+}
@@ -0,0 +1,122 @@
+package scss
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompile(t *testing.T) {
+	public := fstest.MapFS{
+		"css/site.scss": &fstest.MapFile{Data: []byte(`@use "theme";
+@import "base";
+@import url(https://fonts.example.com/font.css);
+
+// Line comment
+$gap: 8px !default;
+$gap: 100px !default;
+
+@mixin card($pad, $radius: 4px) {
+  padding: $pad;
+  border-radius: $radius;
+  @content;
+}
+
+.nav {
+  color: theme.$primary;
+  margin: 0 ($gap * 2);
+  /* block
+     comment */
+  a {
+    &:hover, &.active { color: red; }
+  }
+  @media (min-width: #{theme.$bp}) {
+    display: flex;
+  }
+  transform: translateX(-50%) rotate(45deg);
+  background-image: -webkit-linear-gradient(top, #fff, #000), url("a(1).png");
+  @include card($gap + 2px) {
+    background: url(data:image/png;base64,AAAA);
+  }
+}
+
+@font-face {
+  font-family: "Site";
+}
+`)},
+		"css/_base.scss": &fstest.MapFile{Data: []byte(`html { margin: 0; }`)},
+	}
+	includes := fstest.MapFS{
+		"theme/_index.scss": &fstest.MapFile{Data: []byte("$primary: #333;\n$bp: 768px;\n")},
+	}
+
+	css, err := Compile(public, "css/site.scss", includes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `@import url(https://fonts.example.com/font.css);
+html {
+  margin: 0;
+}
+.nav {
+  color: #333;
+  margin: 0 16px;
+  transform: translateX(-50%) rotate(45deg);
+  background-image: -webkit-linear-gradient(top, #fff, #000), url("a(1).png");
+  padding: 10px;
+  border-radius: 4px;
+  background: url(data:image/png;base64,AAAA);
+}
+.nav a:hover, .nav a.active {
+  color: red;
+}
+@media (min-width: 768px) {
+  .nav {
+    display: flex;
+  }
+}
+@font-face {
+  font-family: "Site";
+}
+`
+	if string(css) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, css)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		"a {\n  color: $missing;\n}":                   "main.scss:2: Undefined variable $missing",
+		"@import \"missing\";":                         "main.scss:1: Can't find stylesheet to import: missing",
+		"a {\n  @if $x { color: red; }\n}":             "main.scss:2: @if is not supported",
+		"a {\n  color: red;\n":                         "main.scss:1: Unclosed block 'a'",
+		"@use \"part\";\n@include part.none;":          "main.scss:2: Undefined mixin part.none",
+		"@import \"bad\";":                             "_bad.scss:2: Declarations must be inside a rule",
+		"@use;":                                        "main.scss:1: Expected a stylesheet URL in @use",
+		"@use ;":                                       "main.scss:1: Expected a stylesheet URL in @use",
+		"$c: #333;\na {\n  color: darken($c, 10%);\n}": "main.scss:3: Function darken() is not supported",
+		"$a: 10px;\na {\n  width: $a*2;\n}":            "main.scss:3: Operation 10px*2 needs spaces around the operator",
+		"a {\n  width: 10px + 2em;\n}":                 "main.scss:2: Incompatible units in 10px + 2em",
+	}
+
+	for src, expected := range tests {
+		fsys := fstest.MapFS{
+			"main.scss":  &fstest.MapFile{Data: []byte(src)},
+			"_part.scss": &fstest.MapFile{Data: []byte("$x: 1;")},
+			"_bad.scss":  &fstest.MapFile{Data: []byte("$x: 1;\ncolor: red;")},
+		}
+		_, err := Compile(fsys, "main.scss")
+		if err == nil {
+			t.Errorf("Expected error compiling '%s'", src)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected error '%s'. Got '%s'", expected, err)
+		}
+	}
+
+	if !IsPartial("css/_vars.scss") || IsPartial("css/site.scss") {
+		t.Error("Unexpected IsPartial result")
+	}
+}
@@ -1,111 +1,56 @@
 package main
 
 import (
-	"bytes"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
-	"path"
-	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
-	"github.com/leonelquinteros/thtml/templates"
+	"github.com/leonelquinteros/thtml/server"
 )
 
-// Handler
-type thtmlHandler struct{}
+// newHandler returns the dev server handler configured from the command line options
+func newHandler() http.Handler {
+	minifyOptions := _config.Minify
 
-func (h thtmlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Catch panics
-	defer func() {
-		if err := recover(); err != nil {
-			// Get stack trace
-			stack := make([]byte, 1<<16)
-			runtime.Stack(stack, false)
-
-			// Log panic
-			log.Printf("PANIC! :: %v", err)
-			log.Print(string(stack))
-		}
-
-		// Log
-		log.Printf("%s %s", r.Method, r.URL.String())
-	}()
-
-	// Construct path
-	p := h.cleanPath(_publicPath + r.URL.EscapedPath())
-
-	// Check if file exists and if it's a file
-	if info, err := os.Stat(p); err == nil && !info.IsDir() {
-		// Load comma separated extensions list
-		exts := strings.Split(_exts, ",")
-
-		// Load templates
-		tpl, err := templates.Load(_templatesPath, exts...)
-		if err != nil {
-			log.Fatalf("Error loading templates from '%s': %s", _templatesPath, err)
-		}
-
-		// Configure
-		tpl.Minify(_minify)
-
-		// Render to buffer
-		buff := new(bytes.Buffer)
-		err = tpl.Render(buff, p, nil)
-		if err != nil {
-			buff.Write([]byte(err.Error()))
-		}
-		content := buff.Bytes()
-
-		// Detect content type
-		ext := filepath.Ext(p)
-		switch ext {
-		case ".html":
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-
-		case ".js":
-			w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
-
-		case ".css":
-			w.Header().Set("Content-Type", "text/css; charset=utf-8")
-
-		case ".svg":
-			w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
-
-		default:
-			w.Header().Set("Content-Type", http.DetectContentType(content))
-		}
-
-		// Flush
-		w.Write(content)
-	} else {
-		w.WriteHeader(404)
+	rules, err := ignoreRules()
+	if err != nil {
+		log.Fatalf("Error loading ignore rules: %s", err)
 	}
-}
 
-// cleanPath normalizes requeste filenames
-func (h thtmlHandler) cleanPath(p string) string {
-	p = path.Clean(p)
+	var roots []fs.FS
+	for _, dir := range templatesDirs() {
+		roots = append(roots, os.DirFS(dir))
+	}
 
-	// Catch routes without ".html" and dir names without /index.html
-	if info, err := os.Stat(p); err != nil || info.IsDir() {
-		if info, err := os.Stat(p + ".html"); err == nil && !info.IsDir() {
-			p += ".html"
-		} else if info, err := os.Stat(p + "index.html"); err == nil && !info.IsDir() {
-			p += "index.html"
-		} else if info, err := os.Stat(p + "/index.html"); err == nil && !info.IsDir() {
-			p += "/index.html"
-		}
+	var compress []string
+	if _compress != "" {
+		compress = strings.Split(_compress, ",")
 	}
 
-	return p
+	return server.NewHandler(server.Options{
+		TemplatesRoots:   roots,
+		PublicFS:         publicFS(),
+		Extensions:       strings.Split(_exts, ","),
+		Ignore:           rules,
+		Minify:           _minify,
+		MinifyOptions:    &minifyOptions,
+		CacheDir:         _cachePath,
+		Bundles:          _config.Bundles,
+		SCSSIncludePaths: _config.SCSS.IncludePaths,
+		Redirects:        _config.Redirects,
+		Compress:         compress,
+		CompressLevel:    _compressLevel,
+		CompressMinSize:  _compressMinSize,
+		Logger:           log.Default(),
+	})
 }
 
 func runServer() {
 	// Routes
-	http.Handle("/", thtmlHandler{})
+	http.Handle("/", newHandler())
 
 	// Server
 	s := &http.Server{
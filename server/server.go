@@ -0,0 +1,520 @@
+// Package server provides an http.Handler that renders a thtml website on the fly,
+// so it can be mounted inside any Go web server.
+//
+// Example
+//
+// The following example program serves the website in the "public" directory under the "/site/" prefix,
+// using the templates in the "templates" directory.
+//
+//  package main
+//
+//  import (
+//      "net/http"
+//      "github.com/leonelquinteros/thtml/server"
+//  )
+//
+//  func main() {
+//      h := server.NewHandler(server.Options{
+//          TemplatesDir: "templates",
+//          PublicDir:    "public",
+//          Prefix:       "/site",
+//          Data: func(r *http.Request) interface{} {
+//              return map[string]string{"Path": r.URL.Path}
+//          },
+//      })
+//
+//      http.Handle("/site/", h)
+//      http.ListenAndServe(":8080", nil)
+//  }
+//
+package server
+
+import (
+	"bytes"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+// Options configures the Handler.
+type Options struct {
+	// Templates directory. TemplatesFS takes precedence when set.
+	TemplatesDir string
+	TemplatesFS  fs.FS
+
+	// TemplatesRoots are multiple template file systems where later roots override templates with the same name
+	// in earlier ones, like a project on top of a theme. Takes precedence over TemplatesFS and TemplatesDir when set.
+	TemplatesRoots []fs.FS
+
+	// Web root directory. PublicFS takes precedence when set.
+	PublicDir string
+	PublicFS  fs.FS
+
+	// Filename extensions parsed as templates. Defaults to ".html".
+	Extensions []string
+
+	// Ignore rules of the public and templates files, which are neither served nor loaded.
+	// Files and directories of the public directory starting with an underscore are private and never served.
+	Ignore *templates.IgnoreRules
+
+	// Minify the output, configured by MinifyOptions or templates.DefaultMinifyOptions() when nil.
+	Minify        bool
+	MinifyOptions *templates.MinifyOptions
+
+	// Processed assets cache directory.
+	CacheDir string
+
+	// Bundles of public files served on the fly, by output name. See templates.Service.Bundles().
+	Bundles map[string][]string
+
+	// Directories where SCSS imports are resolved when not found relative to the importing file.
+	// Requests for a missing ".css" file compile the ".scss" source with the same name on the fly.
+	SCSSIncludePaths []string
+
+	// Redirects of missing paths, answered with their status code.
+	// The rules of the public "_redirects" file and the front matter aliases of pages are also followed.
+	// See templates.Service.RedirectRules().
+	Redirects []templates.Redirect
+
+	// Encodings to negotiate with Accept-Encoding for compressible responses.
+	Compress        []string
+	CompressLevel   int
+	CompressMinSize int
+
+	// Prefix is stripped from the request path before resolving files, to mount the handler under a sub-path.
+	// It's prepended to the URLs of processed images and component assets. See templates.Service.BasePath().
+	Prefix string
+
+	// Middleware wraps the handler. The first one is the outermost.
+	Middleware []func(http.Handler) http.Handler
+
+	// Data returns the data passed to the rendered page of each request.
+	Data func(r *http.Request) interface{}
+
+	// Error writes the response when a page can't be rendered and the public directory has no status page
+	// for the status code, like "404.html". Uses DefaultError when nil. See templates.StatusPage.
+	Error func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+	// Logger logs requests and errors. Nothing is logged when nil.
+	Logger *log.Logger
+}
+
+// Handler renders the files of a public directory through templates on every request.
+// Templates are loaded once and reloaded only when files in the templates directory change.
+// Requests for a missing ".js" file transpile the ".ts", ".tsx" or ".jsx" source with the same name, if any.
+type Handler struct {
+	opts      Options
+	public    fs.FS
+	templates []fs.FS
+	next      http.Handler
+
+	// Loaded templates cache
+	mu          sync.Mutex
+	tpl         *templates.Service
+	fingerprint string
+	checked     time.Time
+	failed      string
+	failedErr   error
+}
+
+// NewHandler returns a Handler configured with the provided options, wrapped by its middleware.
+func NewHandler(opts Options) http.Handler {
+	h := &Handler{
+		opts:   opts,
+		public: opts.PublicFS,
+	}
+	if h.public == nil {
+		h.public = os.DirFS(opts.PublicDir)
+	}
+	h.templates = opts.TemplatesRoots
+	if len(h.templates) == 0 && opts.TemplatesFS != nil {
+		h.templates = []fs.FS{opts.TemplatesFS}
+	}
+	if len(h.templates) == 0 {
+		h.templates = []fs.FS{os.DirFS(opts.TemplatesDir)}
+	}
+	if h.opts.Error == nil {
+		h.opts.Error = DefaultError
+	}
+
+	var next http.Handler = http.HandlerFunc(h.serve)
+	for i := len(opts.Middleware) - 1; i >= 0; i-- {
+		next = opts.Middleware[i](next)
+	}
+	h.next = next
+
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.next.ServeHTTP(w, r)
+}
+
+// DefaultError writes the status code and the error message as plain text.
+func DefaultError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if err != nil {
+		w.Write([]byte(err.Error()))
+	}
+}
+
+// ContentType returns the Content-Type header value for the named file and its content.
+func ContentType(name string, content []byte) string {
+	return templates.ContentType(name, content)
+}
+
+// CleanPath normalizes a request path into a filename of the public file system.
+// Routes without ".html" and directories without "index.html" are resolved to existing files.
+func CleanPath(public fs.FS, p string) string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		p = "."
+	}
+
+	// Catch routes without ".html" and dir names without /index.html
+	if info, err := fs.Stat(public, p); err != nil || info.IsDir() {
+		if info, err := fs.Stat(public, p+".html"); err == nil && !info.IsDir() {
+			p += ".html"
+		} else if info, err := fs.Stat(public, path.Join(p, "index.html")); err == nil && !info.IsDir() {
+			p = path.Join(p, "index.html")
+		}
+	}
+
+	return p
+}
+
+func (h *Handler) logf(format string, v ...interface{}) {
+	if h.opts.Logger != nil {
+		h.opts.Logger.Printf(format, v...)
+	}
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	// Catch panics
+	defer func() {
+		if err := recover(); err != nil {
+			// Get stack trace
+			stack := make([]byte, 1<<16)
+			runtime.Stack(stack, false)
+
+			// Log panic
+			h.logf("PANIC! :: %v", err)
+			h.logf("%s", stack)
+		}
+
+		// Log
+		h.logf("%s %s", r.Method, r.URL.String())
+	}()
+
+	// Mount prefix
+	urlPath := r.URL.Path
+	if h.opts.Prefix != "" {
+		prefix := "/" + strings.Trim(h.opts.Prefix, "/")
+		if urlPath != prefix && !strings.HasPrefix(urlPath, prefix+"/") {
+			h.error(w, r, http.StatusNotFound, nil)
+			return
+		}
+		urlPath = strings.TrimPrefix(urlPath, prefix)
+	}
+
+	// Construct path
+	p := CleanPath(h.public, urlPath)
+
+	// Check if file exists and if it's a file
+	if info, err := fs.Stat(h.public, p); err != nil || info.IsDir() {
+		// Serve bundles, compiled stylesheets and transpiled scripts
+		ext := path.Ext(strings.TrimSuffix(p, ".map"))
+		if len(h.opts.Bundles) > 0 || ext == ".css" || ext == ".js" {
+			tpl, err := h.load()
+			if err != nil {
+				h.logf("Error loading templates: %s", err)
+				h.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			if tpl.IsBundle(p) {
+				content, sourceMap, err := tpl.Bundle(strings.TrimSuffix(p, ".map"))
+				if err != nil {
+					h.logf("Error building bundle '%s': %s", p, err)
+					h.error(w, r, http.StatusInternalServerError, err)
+					return
+				}
+				if strings.HasSuffix(p, ".map") {
+					content = sourceMap
+				}
+				h.write(w, r, p, content)
+				return
+			}
+			if tpl.IsScript(p) {
+				content, sourceMap, err := tpl.Script(strings.TrimSuffix(p, ".map"))
+				if err != nil {
+					h.logf("Error compiling '%s': %s", p, err)
+					h.error(w, r, http.StatusInternalServerError, err)
+					return
+				}
+				if strings.HasSuffix(p, ".map") {
+					content = sourceMap
+				}
+				h.write(w, r, p, content)
+				return
+			}
+			if tpl.IsSCSS(p) {
+				content, err := tpl.Stylesheet(p)
+				if err != nil {
+					h.logf("Error compiling '%s': %s", p, err)
+					h.error(w, r, http.StatusInternalServerError, err)
+					return
+				}
+				h.write(w, r, p, content)
+				return
+			}
+		}
+
+		// Serve processed assets from cache
+		if cached, ok := templates.CachedAsset(h.opts.CacheDir, urlPath); ok {
+			http.ServeFile(w, r, cached)
+			return
+		}
+
+		// Redirect moved pages
+		tpl, err := h.load()
+		if err != nil {
+			h.logf("Error loading templates: %s", err)
+			h.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		rule, ok, err := tpl.MatchRedirect(urlPath)
+		if err != nil {
+			h.logf("Error loading redirects: %s", err)
+			h.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if ok {
+			to := rule.To
+			if h.opts.Prefix != "" && strings.HasPrefix(to, "/") {
+				to = "/" + strings.Trim(h.opts.Prefix, "/") + to
+			}
+			http.Redirect(w, r, to, rule.Status)
+			return
+		}
+
+		h.error(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	// Hide private and ignored files
+	if templates.IsPrivate(p) || h.opts.Ignore.Ignored(p) {
+		h.error(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	// Status pages render with their own status code, as for the responses using them
+	if code, ok := templates.IsStatusPage(p); ok {
+		h.error(w, r, code, nil)
+		return
+	}
+
+	// Load templates
+	tpl, err := h.load()
+	if err != nil {
+		h.logf("Error loading templates: %s", err)
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Request data
+	var data interface{}
+	if h.opts.Data != nil {
+		data = h.opts.Data(r)
+	}
+
+	// Render to buffer
+	buff := new(bytes.Buffer)
+	err = tpl.RenderFile(buff, p, data)
+	if err != nil {
+		h.logf("Error rendering '%s': %s", p, err)
+		h.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Show unpublished pages with a badge
+	content := buff.Bytes()
+	if status, err := tpl.PageStatus(p); err == nil && status != "" && strings.HasPrefix(ContentType(p, content), "text/html") {
+		content = StatusBadge(content, status)
+	}
+
+	h.write(w, r, p, content)
+}
+
+// StatusBadge adds a visible badge with the publishing status (i.e. "draft") to an HTML page,
+// right before its closing body tag or at the end when it's missing.
+func StatusBadge(content []byte, status string) []byte {
+	badge := `<div style="position:fixed;top:8px;right:8px;z-index:2147483647;padding:4px 10px;` +
+		`background:#d93025;color:#fff;font:bold 12px/1.5 sans-serif;text-transform:uppercase;border-radius:4px">` +
+		html.EscapeString(status) + `</div>`
+
+	i := bytes.LastIndex(bytes.ToLower(content), []byte("</body>"))
+	if i < 0 {
+		return append(content, badge...)
+	}
+
+	out := make([]byte, 0, len(content)+len(badge))
+	out = append(out, content[:i]...)
+	out = append(out, badge...)
+	return append(out, content[i:]...)
+}
+
+// error writes the status page of the status code, rendered with a templates.StatusPage,
+// or calls the Error option when there's none or it fails to render.
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, err error) {
+	name := strconv.Itoa(status) + ".html"
+	if info, e := fs.Stat(h.public, name); e != nil || info.IsDir() || h.opts.Ignore.Ignored(name) {
+		h.opts.Error(w, r, status, err)
+		return
+	}
+
+	tpl, e := h.load()
+	if e != nil {
+		h.opts.Error(w, r, status, err)
+		return
+	}
+
+	data := templates.StatusPage{Status: status, Path: r.URL.Path}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	if h.opts.Data != nil {
+		data.Data = h.opts.Data(r)
+	}
+
+	buff := new(bytes.Buffer)
+	e = tpl.RenderFile(buff, name, data)
+	if e != nil {
+		h.logf("Error rendering '%s': %s", name, e)
+		h.opts.Error(w, r, status, err)
+		return
+	}
+
+	h.writeStatus(w, r, name, status, buff.Bytes())
+}
+
+// write sends the content of the public file p, compressed when accepted by the client.
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, p string, content []byte) {
+	h.writeStatus(w, r, p, http.StatusOK, content)
+}
+
+// writeStatus sends the content of the public file p with the status code, compressed when accepted by the client.
+func (h *Handler) writeStatus(w http.ResponseWriter, r *http.Request, p string, status int, content []byte) {
+	// Detect content type
+	w.Header().Set("Content-Type", ContentType(p, content))
+
+	// Compress
+	if len(h.opts.Compress) > 0 && templates.Compressible(p) && len(content) >= h.opts.CompressMinSize {
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc := templates.Negotiate(r.Header.Get("Accept-Encoding"), h.opts.Compress)
+		if enc != "" {
+			compressed := new(bytes.Buffer)
+			err := templates.Encode(compressed, enc, h.opts.CompressLevel, content)
+			if err != nil {
+				h.logf("Error compressing '%s': %s", p, err)
+			} else {
+				w.Header().Set("Content-Encoding", enc)
+				content = compressed.Bytes()
+			}
+		}
+	}
+
+	// Flush
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+// load returns the cached templates.Service, reloading it when the templates changed since the last load.
+// When the templates fail to parse, the error is returned and the previous templates are kept
+// to be used again if the files are restored.
+// The templates are checked for changes at most once every templates.WatchInterval.
+func (h *Handler) load() (*templates.Service, error) {
+	h.mu.Lock()
+	if h.tpl != nil && h.failedErr == nil && time.Since(h.checked) < templates.WatchInterval {
+		tpl := h.tpl
+		h.mu.Unlock()
+		return tpl, nil
+	}
+	h.mu.Unlock()
+
+	fp, err := templates.Fingerprint(h.templates...)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tpl != nil && fp == h.fingerprint {
+		h.checked = time.Now()
+		return h.tpl, nil
+	}
+	if h.failedErr != nil && fp == h.failed {
+		return nil, h.failedErr
+	}
+
+	// First load
+	if h.tpl == nil {
+		tpl := templates.New(h.opts.Extensions...)
+		tpl.Ignore(h.opts.Ignore)
+		err = tpl.LoadFS(h.templates...)
+		if err != nil {
+			h.failed, h.failedErr = fp, err
+			return nil, err
+		}
+
+		// Configure
+		tpl.Minify(h.opts.Minify)
+		if h.opts.MinifyOptions != nil {
+			tpl.MinifyOptions(*h.opts.MinifyOptions)
+		}
+		tpl.Cache(h.opts.CacheDir)
+		tpl.BasePath(h.opts.Prefix)
+		tpl.PublicFS(h.public)
+		tpl.SCSSIncludePaths(h.opts.SCSSIncludePaths...)
+		err = tpl.Bundles(h.opts.Bundles)
+		if err != nil {
+			h.failed, h.failedErr = fp, err
+			return nil, err
+		}
+		err = tpl.Redirects(h.opts.Redirects)
+		if err != nil {
+			h.failed, h.failedErr = fp, err
+			return nil, err
+		}
+
+		h.tpl, h.fingerprint, h.checked = tpl, fp, time.Now()
+		h.failed, h.failedErr = "", nil
+
+		return tpl, nil
+	}
+
+	// Reload
+	err = h.tpl.Reload()
+	if err != nil {
+		h.failed, h.failedErr = fp, err
+		return nil, err
+	}
+	h.logf("Templates changed, reloaded")
+
+	h.fingerprint, h.checked = fp, time.Now()
+	h.failed, h.failedErr = "", nil
+
+	return h.tpl, nil
+}
@@ -0,0 +1,420 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+func TestHandlerFS(t *testing.T) {
+	h := NewHandler(Options{
+		TemplatesDir: "../_example/templates",
+		PublicFS: fstest.MapFS{
+			"index.html":      &fstest.MapFile{Data: []byte(`{{ template "layouts/default.html" }}`)},
+			"docs/index.html": &fstest.MapFile{Data: []byte(`Docs`)},
+			"style.css":       &fstest.MapFile{Data: []byte(`body {}`)},
+		},
+	})
+
+	tests := map[string]int{
+		"/":           200,
+		"/index":      200,
+		"/docs":       200,
+		"/docs/":      200,
+		"/style.css":  200,
+		"/not-found":  404,
+		"/../secrets": 404,
+	}
+	for p, code := range tests {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", p, nil)
+		h.ServeHTTP(resp, req)
+		if resp.Code != code {
+			t.Errorf("%s: Expected response code %d. Got %d", p, code, resp.Code)
+		}
+	}
+}
+
+func TestHandlerBundles(t *testing.T) {
+	public := fstest.MapFS{
+		"css/a.css": &fstest.MapFile{Data: []byte(`a {}`)},
+		"css/b.css": &fstest.MapFile{Data: []byte(`b {}`)},
+	}
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{},
+		PublicFS:    public,
+		Bundles:     map[string][]string{"css/site.css": {"css/*.css"}},
+	})
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/css/site.css", nil))
+	if resp.Code != 200 {
+		t.Fatalf("Expected response code 200. Got %d", resp.Code)
+	}
+	if !strings.HasPrefix(resp.Body.String(), "a {}\nb {}\n") {
+		t.Errorf("Expected bundled content. Got '%s'", resp.Body.String())
+	}
+
+	// Changes are served on the fly
+	public["css/b.css"] = &fstest.MapFile{Data: []byte(`b { color: red; }`)}
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/css/site.css", nil))
+	if !strings.Contains(resp.Body.String(), "color: red") {
+		t.Errorf("Expected updated bundle. Got '%s'", resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/css/site.css.map", nil))
+	if resp.Code != 200 || !strings.Contains(resp.Body.String(), `"/css/a.css"`) {
+		t.Errorf("Expected source map. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+}
+
+func TestHandlerStylesheets(t *testing.T) {
+	public := fstest.MapFS{
+		"css/site.scss": &fstest.MapFile{Data: []byte("$c: red;\na {\n  b { color: $c; }\n}\n")},
+	}
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{},
+		PublicFS:    public,
+	})
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/css/site.css", nil))
+	if resp.Code != 200 {
+		t.Fatalf("Expected response code 200. Got %d", resp.Code)
+	}
+	if resp.Body.String() != "a b {\n  color: red;\n}\n" {
+		t.Errorf("Expected compiled stylesheet. Got '%s'", resp.Body.String())
+	}
+	if ct := resp.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("Expected text/css content type. Got '%s'", ct)
+	}
+
+	// Compile errors
+	public["css/site.scss"] = &fstest.MapFile{Data: []byte("a {\n  color: $missing;\n}\n")}
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/css/site.css", nil))
+	if resp.Code != 500 || !strings.Contains(resp.Body.String(), "css/site.scss:2") {
+		t.Errorf("Expected compile error. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/css/other.css", nil))
+	if resp.Code != 404 {
+		t.Errorf("Expected response code 404. Got %d", resp.Code)
+	}
+}
+
+func TestHandlerScripts(t *testing.T) {
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{},
+		PublicFS: fstest.MapFS{
+			"js/app.ts":    &fstest.MapFile{Data: []byte("const n: number = 1;\nconsole.log(n);\n")},
+			"js/broken.ts": &fstest.MapFile{Data: []byte("const a = ;\n")},
+		},
+	})
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/js/app.js", nil))
+	if resp.Code != 200 || !strings.Contains(resp.Body.String(), "console.log(n)") {
+		t.Errorf("Expected transpiled script. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+	if ct := resp.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("Expected javascript content type. Got '%s'", ct)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/js/app.js.map", nil))
+	if resp.Code != 200 || !strings.Contains(resp.Body.String(), `"app.ts"`) {
+		t.Errorf("Expected source map. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/js/broken.js", nil))
+	if resp.Code != 500 || !strings.Contains(resp.Body.String(), "js/broken.ts:1:11") {
+		t.Errorf("Expected compile error. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+}
+
+func TestHandlerDrafts(t *testing.T) {
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{},
+		PublicFS: fstest.MapFS{
+			"draft.html":     &fstest.MapFile{Data: []byte("---\ndraft: true\n---\n<html><body><h1>Draft</h1></body></html>")},
+			"published.html": &fstest.MapFile{Data: []byte("---\ntitle: Published\n---\n<h1>{{ (frontMatter).title }}</h1>")},
+		},
+	})
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/draft", nil))
+	if resp.Code != 200 {
+		t.Fatalf("Expected response code 200. Got %d", resp.Code)
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, "<h1>Draft</h1><div") || !strings.HasSuffix(body, "draft</div></body></html>") {
+		t.Errorf("Expected draft badge. Got '%s'", body)
+	}
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/published", nil))
+	if resp.Body.String() != "<h1>Published</h1>" {
+		t.Errorf("Expected page without badge. Got '%s'", resp.Body.String())
+	}
+}
+
+func TestHandlerIgnore(t *testing.T) {
+	rules, err := templates.NewIgnoreRules("*.bak", "vendor/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{},
+		PublicFS: fstest.MapFS{
+			"index.html":     &fstest.MapFile{Data: []byte("Home")},
+			"index.html.bak": &fstest.MapFile{Data: []byte("Old")},
+			"_drafts/a.html": &fstest.MapFile{Data: []byte("Draft")},
+			"vendor/a.scss":  &fstest.MapFile{Data: []byte("a { color: red; }")},
+			"vendor/b.ts":    &fstest.MapFile{Data: []byte("let b: number = 1")},
+		},
+		Ignore: rules,
+	})
+
+	for p, code := range map[string]int{"/": 200, "/index.html.bak": 404, "/_drafts/a": 404, "/vendor/a.css": 404, "/vendor/b.js": 404} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest("GET", p, nil))
+		if resp.Code != code {
+			t.Errorf("Expected response code %d for %s. Got %d", code, p, resp.Code)
+		}
+	}
+}
+
+func TestHandlerOptions(t *testing.T) {
+	var calls []string
+
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{
+			"greeting.html": &fstest.MapFile{Data: []byte(`Hello {{ . }}`)},
+		},
+		PublicFS: fstest.MapFS{
+			"index.html":  &fstest.MapFile{Data: []byte(`{{ template "greeting.html" .Name }}`)},
+			"broken.html": &fstest.MapFile{Data: []byte(`{{ template "missing.html" }}`)},
+		},
+		Prefix: "/site/",
+		Data: func(r *http.Request) interface{} {
+			return map[string]string{"Name": r.URL.Query().Get("name")}
+		},
+		Middleware: []func(http.Handler) http.Handler{
+			func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, "first")
+					next.ServeHTTP(w, r)
+				})
+			},
+			func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, "second")
+					next.ServeHTTP(w, r)
+				})
+			},
+		},
+		Error: func(w http.ResponseWriter, r *http.Request, status int, err error) {
+			if err == nil {
+				err = errors.New("not found")
+			}
+			w.WriteHeader(status)
+			w.Write([]byte("custom: " + err.Error()))
+		},
+	})
+
+	// Data and prefix
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/site/?name=World", nil)
+	h.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Fatalf("Expected response code 200. Got %d", resp.Code)
+	}
+	if resp.Body.String() != "Hello World" {
+		t.Errorf("Expected 'Hello World'. Got '%s'", resp.Body.String())
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("Expected middleware to be called in order. Got %v", calls)
+	}
+
+	// Outside prefix
+	resp = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/index.html", nil)
+	h.ServeHTTP(resp, req)
+	if resp.Code != 404 || resp.Body.String() != "custom: not found" {
+		t.Errorf("Expected custom 404 response. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+
+	// Render error
+	resp = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/site/broken", nil)
+	h.ServeHTTP(resp, req)
+	if resp.Code != 500 {
+		t.Errorf("Expected response code 500. Got %d", resp.Code)
+	}
+}
+
+func TestHandlerReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tplFile := filepath.Join(dir, "greeting.html")
+	write := func(content string, mtime time.Time) {
+		err := ioutil.WriteFile(tplFile, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(tplFile, mtime, mtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHandler(Options{
+		TemplatesDir: dir,
+		PublicFS: fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`{{ template "greeting.html" }}`)},
+		},
+	})
+	get := func() (int, string) {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest("GET", "/", nil))
+		return resp.Code, resp.Body.String()
+	}
+
+	now := time.Now()
+	write("Hello", now.Add(-time.Hour))
+	if code, body := get(); code != 200 || body != "Hello" {
+		t.Fatalf("Expected 200 'Hello'. Got %d '%s'", code, body)
+	}
+
+	// Cached
+	handler := h.(*Handler)
+	loaded := handler.tpl
+	get()
+	if handler.tpl != loaded {
+		t.Error("Expected templates not to be reloaded without changes")
+	}
+
+	// Changes are checked once every WatchInterval
+	interval := templates.WatchInterval
+	defer func() {
+		templates.WatchInterval = interval
+	}()
+	templates.WatchInterval = time.Hour
+	write("Bye", now.Add(-time.Minute))
+	if code, body := get(); code != 200 || body != "Hello" {
+		t.Fatalf("Expected 200 'Hello' before the next check. Got %d '%s'", code, body)
+	}
+
+	// Changed
+	templates.WatchInterval = 0
+	if code, body := get(); code != 200 || body != "Bye" {
+		t.Fatalf("Expected 200 'Bye'. Got %d '%s'", code, body)
+	}
+
+	// Parse error keeps the previous templates
+	loaded = handler.tpl
+	write("{{ broken", now)
+	if code, _ := get(); code != 500 {
+		t.Fatalf("Expected response code 500. Got %d", code)
+	}
+	if handler.tpl != loaded {
+		t.Error("Expected previous templates to be kept after a parse error")
+	}
+
+	// Fixed
+	write("Fixed", now.Add(time.Minute))
+	if code, body := get(); code != 200 || body != "Fixed" {
+		t.Fatalf("Expected 200 'Fixed'. Got %d '%s'", code, body)
+	}
+}
+
+func TestHandlerRedirects(t *testing.T) {
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{},
+		PublicFS: fstest.MapFS{
+			"_redirects":   &fstest.MapFile{Data: []byte("/temp.html /index.html 302\n")},
+			"index.html":   &fstest.MapFile{Data: []byte("Home")},
+			"about.html":   &fstest.MapFile{Data: []byte("---\naliases: /about-us/\n---\nAbout")},
+			"stays.html":   &fstest.MapFile{Data: []byte("Stays")},
+			"broken.html":  &fstest.MapFile{Data: []byte("---\naliases: /broken-old/\nBroken")},
+			"invalid.html": &fstest.MapFile{Data: []byte("---\naliases: invalid-old\n---\nInvalid")},
+		},
+		Redirects: []templates.Redirect{
+			{From: "/old.html", To: "/about.html"},
+			{From: "/stays.html", To: "/index.html"},
+		},
+		Prefix: "/site",
+	})
+
+	for p, expected := range map[string]struct {
+		code     int
+		location string
+	}{
+		"/site/old":        {301, "/site/about.html"},
+		"/site/about-us/":  {301, "/site/about.html"},
+		"/site/temp.html":  {302, "/site/index.html"},
+		"/site/stays.html": {200, ""},
+		"/site/missing":    {404, ""},
+		"/site/broken-old": {404, ""},
+		"/site/broken":     {500, ""},
+	} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest("GET", p, nil))
+		if resp.Code != expected.code || resp.Header().Get("Location") != expected.location {
+			t.Errorf("Expected %d '%s' for %s. Got %d '%s'", expected.code, expected.location, p, resp.Code, resp.Header().Get("Location"))
+		}
+	}
+}
+
+func TestHandlerStatusPages(t *testing.T) {
+	h := NewHandler(Options{
+		TemplatesFS: fstest.MapFS{
+			"layout.html": &fstest.MapFile{Data: []byte(`<h1>{{ .Status }}</h1>`)},
+		},
+		PublicFS: fstest.MapFS{
+			"index.html":  &fstest.MapFile{Data: []byte("Home")},
+			"broken.html": &fstest.MapFile{Data: []byte(`{{ index . 1 }}`)},
+			"404.html":    &fstest.MapFile{Data: []byte(`{{ template "layout.html" . }}{{ .Path }} not found`)},
+		},
+	})
+
+	for p, expected := range map[string]struct {
+		code int
+		body string
+	}{
+		"/":         {200, "Home"},
+		"/missing":  {404, "<h1>404</h1>/missing not found"},
+		"/404.html": {404, "<h1>404</h1>/404.html not found"},
+	} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest("GET", p, nil))
+		if resp.Code != expected.code || resp.Body.String() != expected.body {
+			t.Errorf("Expected %d '%s' for %s. Got %d '%s'", expected.code, expected.body, p, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Without status page
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest("GET", "/broken", nil))
+	if resp.Code != 500 || !strings.Contains(resp.Body.String(), "error calling index") {
+		t.Errorf("Expected default 500 error. Got %d '%s'", resp.Code, resp.Body.String())
+	}
+}
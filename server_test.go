@@ -11,7 +11,7 @@ func TestServeHTTP(t *testing.T) {
 	_templatesPath = "_example/templates"
 
 	// Init handler
-	h := thtmlHandler{}
+	h := newHandler()
 
 	// Test requests
 	resp := httptest.NewRecorder()
@@ -42,3 +42,36 @@ func TestServeHTTP(t *testing.T) {
 		t.Fatalf("Expected response code 404. Got %d", resp.Code)
 	}
 }
+
+func TestServeHTTPCompress(t *testing.T) {
+	// Init config
+	_publicPath = "_example/public"
+	_templatesPath = "_example/templates"
+	compress, minSize := _compress, _compressMinSize
+	_compress = "br,gzip"
+	_compressMinSize = 0
+	defer func() {
+		_compress, _compressMinSize = compress, minSize
+	}()
+
+	// Init handler
+	h := newHandler()
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/index.html", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	h.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Fatalf("Expected response code 200. Got %d", resp.Code)
+	}
+	if enc := resp.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Expected gzip Content-Encoding. Got '%s'", enc)
+	}
+
+	resp = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/index.html", nil)
+	h.ServeHTTP(resp, req)
+	if enc := resp.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("Expected no Content-Encoding. Got '%s'", enc)
+	}
+}
@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ComponentAssetsDir is the output directory of the bundles of component assets.
+const ComponentAssetsDir string = "/_components/"
+
+// componentStyles returns a <link> tag to the bundle of the CSS files co-located with the templates used by page.
+func (s *Service) componentStyles(tpl *template.Template, page string) (string, error) {
+	url, err := s.componentAssets(tpl, page, ".css")
+	if err != nil || url == "" {
+		return "", err
+	}
+
+	return `<link rel="stylesheet" href="` + s.basePath + url + `">`, nil
+}
+
+// componentScripts returns a <script> tag to the bundle of the JS files co-located with the templates used by page.
+func (s *Service) componentScripts(tpl *template.Template, page string) (string, error) {
+	url, err := s.componentAssets(tpl, page, ".js")
+	if err != nil || url == "" {
+		return "", err
+	}
+
+	return `<script src="` + s.basePath + url + `"></script>`, nil
+}
+
+// componentAssets bundles the files with the provided extension next to the templates used by page
+// (i.e. "components/nav.css" for "components/nav.html") into the cache directory, and returns the bundle URL.
+// Bundles are named after their content, so pages using the same components share them.
+// It returns an empty URL when no template used by page has assets.
+func (s *Service) componentAssets(tpl *template.Template, page, ext string) (string, error) {
+	s.Lock()
+	roots := s.tplRoots
+	s.Unlock()
+
+	// Collect
+	buff := new(bytes.Buffer)
+	for _, name := range usedTemplates(tpl, page) {
+		asset := strings.TrimSuffix(name, path.Ext(name)) + ext
+		content, ok := readRoots(roots, asset)
+		if !ok {
+			continue
+		}
+
+		buff.Write(content)
+		if ext == ".js" {
+			buff.WriteString(";")
+		}
+		buff.WriteString("\n")
+	}
+	if buff.Len() == 0 {
+		return "", nil
+	}
+
+	// Minify
+	bundle := new(bytes.Buffer)
+	err := s.flush(bundle, ComponentAssetsDir+"bundle"+ext, buff)
+	if err != nil {
+		return "", err
+	}
+
+	// Cache
+	sum := sha256.Sum256(bundle.Bytes())
+	url := ComponentAssetsDir + hex.EncodeToString(sum[:])[:16] + ext
+	cached, ok := s.Cached(url)
+	if !ok {
+		cached = filepath.Join(s.cacheRoot(), filepath.FromSlash(url))
+		err = os.MkdirAll(filepath.Dir(cached), 0755)
+		if err != nil {
+			return "", NewError("Error caching component assets " + url + ": " + err.Error())
+		}
+		err = ioutil.WriteFile(cached, bundle.Bytes(), 0644)
+		if err != nil {
+			return "", NewError("Error caching component assets " + url + ": " + err.Error())
+		}
+	}
+	s.generate(url, cached)
+
+	return url, nil
+}
+
+// readRoots returns the content of the named file in the last root containing it.
+func readRoots(roots []fs.FS, name string) ([]byte, bool) {
+	for i := len(roots) - 1; i >= 0; i-- {
+		content, err := fs.ReadFile(roots[i], name)
+		if err == nil {
+			return content, true
+		}
+	}
+
+	return nil, false
+}
+
+// usedTemplates returns the named template of the set and all templates it uses through
+// {{ template }}, "component" and "partial" calls with constant names, in first use order.
+func usedTemplates(tpl *template.Template, name string) []string {
+	if tpl == nil {
+		return nil
+	}
+
+	var used []string
+	seen := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		t := tpl.Lookup(name)
+		if seen[name] || t == nil || t.Tree == nil {
+			return
+		}
+		seen[name] = true
+		used = append(used, name)
+
+		walkTree(t.Tree.Root, func(node parse.Node) {
+			switch n := node.(type) {
+			case *parse.TemplateNode:
+				visit(n.Name)
+
+			case *parse.CommandNode:
+				if len(n.Args) < 2 {
+					return
+				}
+				id, ok := n.Args[0].(*parse.IdentifierNode)
+				if !ok {
+					return
+				}
+				str, ok := n.Args[1].(*parse.StringNode)
+				if !ok {
+					return
+				}
+
+				switch id.Ident {
+				case "component":
+					if c, err := lookupComponent(tpl, str.Text); err == nil {
+						visit(c.Name())
+					}
+
+				case "partial":
+					visit(str.Text)
+				}
+			}
+		})
+	}
+	visit(name)
+
+	return used
+}
@@ -0,0 +1,85 @@
+package templates
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestComponentAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tplFS := fstest.MapFS{
+		"layouts/base.html":     &fstest.MapFile{Data: []byte(`<head>{{ componentStyles }}</head>{{ template "components/nav.html" }}{{ block "content" . }}{{ end }}{{ componentScripts }}`)},
+		"components/nav.html":   &fstest.MapFile{Data: []byte(`<nav></nav>`)},
+		"components/nav.css":    &fstest.MapFile{Data: []byte(`nav { color: red; }`)},
+		"components/nav.js":     &fstest.MapFile{Data: []byte(`var nav = 1`)},
+		"components/card.html":  &fstest.MapFile{Data: []byte(`<div>{{ template "components/nav.html" }}</div>`)},
+		"components/card.css":   &fstest.MapFile{Data: []byte(`.card { margin: 0; }`)},
+		"components/plain.html": &fstest.MapFile{Data: []byte(`<p></p>`)},
+	}
+	publicFS := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`{{ template "layouts/base.html" }}{{ define "content" }}{{ component "card" }}{{ end }}`)},
+		"about.html": &fstest.MapFile{Data: []byte(`{{ template "layouts/base.html" }}{{ define "content" }}{{ component "plain" }}{{ end }}`)},
+		"plain.html": &fstest.MapFile{Data: []byte(`{{ componentStyles }}{{ component "plain" }}`)},
+	}
+
+	s, err := LoadFS(tplFS, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Cache(dir)
+
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := regexp.MustCompile(`href="/(_components/[0-9a-f]+\.css)"`)
+	script := regexp.MustCompile(`src="/(_components/[0-9a-f]+\.js)"`)
+	bundle := func(page string, re *regexp.Regexp) string {
+		content, err := fs.ReadFile(out, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := re.FindStringSubmatch(string(content))
+		if m == nil {
+			t.Fatalf("Expected bundle tag in %s. Got '%s'", page, content)
+		}
+		bundle, err := fs.ReadFile(out, m[1])
+		if err != nil {
+			t.Fatalf("Expected bundle %s in build output: %s", m[1], err)
+		}
+		return string(bundle)
+	}
+
+	// Used components only, deduplicated
+	css := bundle("index.html", link)
+	if strings.Count(css, "nav {") != 1 || !strings.Contains(css, ".card {") {
+		t.Errorf("Expected nav and card styles once. Got '%s'", css)
+	}
+	if css = bundle("about.html", link); strings.Contains(css, ".card") || !strings.Contains(css, "nav {") {
+		t.Errorf("Expected nav styles only. Got '%s'", css)
+	}
+	if js := bundle("index.html", script); !strings.Contains(js, "var nav = 1") {
+		t.Errorf("Expected nav script. Got '%s'", js)
+	}
+
+	// No assets, no tags
+	content, err := fs.ReadFile(out, "plain.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "<p></p>" {
+		t.Errorf("Expected no tags for pages without component assets. Got '%s'", content)
+	}
+}
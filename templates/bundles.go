@@ -0,0 +1,380 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+)
+
+// cssImport matches @import rules of local stylesheets without media queries.
+var cssImport = regexp.MustCompile(`@import\s+(?:url\(\s*)?["']?([^"'()\s;]+)["']?\s*\)?\s*;`)
+
+// bundleChunk is a piece of a bundle coming from a single source file.
+type bundleChunk struct {
+	source  string
+	line    int
+	content string
+
+	// Kept @import rule, moved to the start of the bundle as CSS ignores it after other rules
+	imp bool
+}
+
+// Bundles sets the bundles built from the public files, by output name (i.e. "css/site.css").
+// Each bundle concatenates the public files matching its list of glob patterns, in order.
+// Local @import rules in stylesheets are replaced by the imported files,
+// and remote ones are moved to the start of the bundle.
+// Only ".css" and ".js" bundles are supported.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Bundles(bundles map[string][]string) error {
+	b := make(map[string][]string, len(bundles))
+	for name, patterns := range bundles {
+		clean := path.Clean("/" + name)[1:]
+		if ext := path.Ext(clean); ext != ".css" && ext != ".js" {
+			return NewError("Error configuring bundle " + name + ": unsupported extension " + ext)
+		}
+		b[clean] = patterns
+	}
+
+	s.Lock()
+	s.bundles = b
+	s.Unlock()
+
+	return nil
+}
+
+// IsBundle returns true if the public file name is the output of a bundle, or of its source map.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) IsBundle(name string) bool {
+	name = path.Clean("/" + name)[1:]
+
+	s.Lock()
+	defer s.Unlock()
+
+	_, ok := s.bundles[strings.TrimSuffix(name, ".map")]
+	return ok
+}
+
+// Bundle returns the content of the named bundle and its source map,
+// built from the public file system and minified when enabled.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Bundle(name string) ([]byte, []byte, error) {
+	name = path.Clean("/" + name)[1:]
+
+	sources, err := s.bundleSources(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Chunks
+	var chunks []bundleChunk
+	imported := make(map[string]bool)
+	for _, src := range sources {
+		c, err := s.bundleChunks(src, path.Ext(name) == ".css", imported)
+		if err != nil {
+			return nil, nil, NewError("Error building bundle " + name + ": " + err.Error())
+		}
+		chunks = append(chunks, c...)
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return chunks[i].imp && !chunks[j].imp
+	})
+
+	// Concatenate, minifying each chunk to keep the source map lines
+	content := new(bytes.Buffer)
+	sm := newSourceMap(path.Base(name))
+	mime := s.minifyType("/" + name)
+	raw := 0
+	for _, c := range chunks {
+		raw += len(c.content)
+
+		// Skip leading empty lines left by replaced imports
+		out := strings.TrimLeft(c.content, "\n")
+		c.line += len(c.content) - len(out)
+		if mime != "" {
+			min, err := s.getMinifier().String(mime, out)
+			if err != nil && err != minify.ErrNotExist {
+				return nil, nil, NewError("Error minifying " + c.source + ": " + err.Error())
+			}
+			if err == nil {
+				out = min
+			}
+		}
+		if strings.TrimSpace(out) == "" {
+			continue
+		}
+		out = strings.TrimRight(out, "\n")
+		if path.Ext(name) == ".js" && !strings.HasSuffix(out, ";") {
+			out += ";"
+		}
+
+		lines := strings.Count(out, "\n") + 1
+		for i := 0; i < lines; i++ {
+			line := c.line
+			if mime == "" {
+				line += i
+			}
+			sm.add("/"+c.source, line)
+		}
+		content.WriteString(out)
+		content.WriteString("\n")
+	}
+
+	// Source map
+	mapContent, err := sm.MarshalJSON()
+	if err != nil {
+		return nil, nil, NewError("Error building source map of bundle " + name + ": " + err.Error())
+	}
+	mapURL := "//# sourceMappingURL=" + path.Base(name) + ".map\n"
+	if path.Ext(name) == ".css" {
+		mapURL = "/*# sourceMappingURL=" + path.Base(name) + ".map */\n"
+	}
+	content.WriteString(mapURL)
+	s.report.rawSize(name, raw+len(mapURL))
+
+	return content.Bytes(), mapContent, nil
+}
+
+// bundleSources returns the public files matching the patterns of the named bundle, in order.
+func (s *Service) bundleSources(name string) ([]string, error) {
+	s.Lock()
+	patterns, ok := s.bundles[name]
+	s.Unlock()
+	if !ok {
+		return nil, NewError("Error building bundle " + name + ": bundle not found")
+	}
+	if s.publicFS == nil {
+		return nil, NewError("Error building bundle " + name + ": public file system not set")
+	}
+
+	var sources []string
+	seen := map[string]bool{name: true}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(s.publicFS, path.Clean("/" + pattern)[1:])
+		if err != nil {
+			return nil, NewError("Error building bundle " + name + ": " + err.Error())
+		}
+		if len(matches) == 0 {
+			return nil, NewError("Error building bundle " + name + ": no files match " + pattern)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				sources = append(sources, m)
+			}
+		}
+	}
+
+	return sources, nil
+}
+
+// bundleChunks reads a bundle source, replacing local @import rules of stylesheets by the imported files.
+// Remote @import rules are returned in their own chunks. Files already imported are skipped.
+func (s *Service) bundleChunks(name string, css bool, imported map[string]bool) ([]bundleChunk, error) {
+	if imported[name] {
+		return nil, nil
+	}
+	imported[name] = true
+
+	content, err := fs.ReadFile(s.publicFS, name)
+	if err != nil {
+		return nil, err
+	}
+	src := string(content)
+	if !css {
+		return []bundleChunk{{source: name, content: src}}, nil
+	}
+
+	var chunks []bundleChunk
+	last := 0
+	for _, m := range cssImport.FindAllStringSubmatchIndex(src, -1) {
+		ref := src[m[2]:m[3]]
+		chunks = append(chunks, bundleChunk{
+			source:  name,
+			line:    strings.Count(src[:last], "\n"),
+			content: src[last:m[0]],
+		})
+		last = m[1]
+
+		if strings.Contains(ref, "://") || strings.HasPrefix(ref, "//") {
+			chunks = append(chunks, bundleChunk{
+				source:  name,
+				line:    strings.Count(src[:m[0]], "\n"),
+				content: src[m[0]:m[1]],
+				imp:     true,
+			})
+			continue
+		}
+
+		// Resolve relative to the importing file or the public root
+		fn := path.Join(path.Dir(name), ref)
+		if strings.HasPrefix(ref, "/") {
+			fn = path.Clean(ref)[1:]
+		}
+		c, err := s.bundleChunks(fn, css, imported)
+		if err != nil {
+			return nil, NewError("Error importing " + ref + " from " + name + ": " + err.Error())
+		}
+		chunks = append(chunks, c...)
+	}
+	chunks = append(chunks, bundleChunk{
+		source:  name,
+		line:    strings.Count(src[:last], "\n"),
+		content: src[last:],
+	})
+
+	return chunks, nil
+}
+
+// Asset returns the URL of the named public file or bundle, with a version query based on its content
+// to be cached by browsers until it changes:
+//
+//  <link rel="stylesheet" href="{{ asset "css/site.css" }}">
+//
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Asset(name string) (string, error) {
+	name = path.Clean("/" + name)[1:]
+
+	var content []byte
+	var err error
+	if s.IsBundle(name) && !strings.HasSuffix(name, ".map") {
+		content, _, err = s.Bundle(name)
+	} else if s.IsSCSS(name) {
+		content, err = s.Stylesheet(name)
+	} else if s.IsScript(name) && !strings.HasSuffix(name, ".map") {
+		content, _, err = s.Script(name)
+	} else if s.publicFS == nil {
+		err = NewError("public file system not set")
+	} else {
+		content, err = fs.ReadFile(s.publicFS, name)
+	}
+	if err != nil {
+		return "", NewError("Error reading asset " + name + ": " + err.Error())
+	}
+
+	sum := sha256.Sum256(content)
+	return "/" + name + "?v=" + hex.EncodeToString(sum[:])[:8], nil
+}
+
+// buildBundles writes all bundles and their source maps to the build output.
+func (s *Service) buildBundles() error {
+	s.Lock()
+	names := make([]string, 0, len(s.bundles))
+	for name := range s.bundles {
+		names = append(names, name)
+	}
+	s.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		sources, err := s.bundleSources(name)
+		if err != nil {
+			return err
+		}
+		s.report.begin(sources...)
+
+		content, sourceMap, err := s.Bundle(name)
+		if err != nil {
+			return err
+		}
+		err = writeFile(s.buildFS, name, content)
+		if err != nil {
+			return NewError("Error writing bundle " + name + ": " + err.Error())
+		}
+		err = s.buildFS.WriteFile(name+".map", sourceMap, 0644)
+		if err != nil {
+			return NewError("Error writing bundle " + name + ".map: " + err.Error())
+		}
+		err = s.precompress(name, content)
+		if err != nil {
+			return NewError("Error compressing bundle " + name + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// sourceMap is a version 3 source map, mapping each generated line to a source line.
+type sourceMap struct {
+	file     string
+	sources  []string
+	index    map[string]int
+	mappings []string
+
+	// Previous segment values, encoded as deltas
+	lastSource int
+	lastLine   int
+}
+
+func newSourceMap(file string) *sourceMap {
+	return &sourceMap{
+		file:  file,
+		index: make(map[string]int),
+	}
+}
+
+// add maps the next generated line to the start of the source line (zero based).
+func (m *sourceMap) add(source string, line int) {
+	i, ok := m.index[source]
+	if !ok {
+		i = len(m.sources)
+		m.index[source] = i
+		m.sources = append(m.sources, source)
+	}
+
+	m.mappings = append(m.mappings, vlq(0)+vlq(i-m.lastSource)+vlq(line-m.lastLine)+vlq(0))
+	m.lastSource, m.lastLine = i, line
+}
+
+// MarshalJSON implements json.Marshaler
+func (m *sourceMap) MarshalJSON() ([]byte, error) {
+	sources := m.sources
+	if sources == nil {
+		sources = []string{}
+	}
+
+	return json.Marshal(struct {
+		Version  int      `json:"version"`
+		File     string   `json:"file"`
+		Sources  []string `json:"sources"`
+		Names    []string `json:"names"`
+		Mappings string   `json:"mappings"`
+	}{
+		Version:  3,
+		File:     m.file,
+		Sources:  sources,
+		Names:    []string{},
+		Mappings: strings.Join(m.mappings, ";"),
+	})
+}
+
+// vlq encodes a source map value as base64 VLQ.
+func vlq(n int) string {
+	const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+	v := n << 1
+	if n < 0 {
+		v = (-n << 1) | 1
+	}
+
+	var out []byte
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		out = append(out, chars[digit])
+		if v == 0 {
+			return string(out)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package templates
+
+import (
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBundles(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"css/reset.css":      &fstest.MapFile{Data: []byte("html {\n  margin: 0;\n}\n")},
+		"css/base.css":       &fstest.MapFile{Data: []byte("@import \"parts/vars.css\";\n@import url(https://fonts.example.com/font.css);\nbody {\n  color: red;\n}\n")},
+		"css/parts/vars.css": &fstest.MapFile{Data: []byte("@import \"../reset.css\";\n:root {\n  --c: red;\n}\n")},
+		"js/a.js":            &fstest.MapFile{Data: []byte("var a = 1")},
+		"js/b.js":            &fstest.MapFile{Data: []byte("var b = 2;\n")},
+		"index.html":         &fstest.MapFile{Data: []byte(`<link href="{{ asset "css/site.css" }}"><script src="{{ asset "js/app.js" }}"></script>`)},
+	}
+
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PublicFS(publicFS)
+	err = s.Bundles(map[string][]string{
+		"css/site.css": {"css/base.css", "css/reset.css"},
+		"/js/app.js":   {"js/*.js"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Bundles(map[string][]string{"img/sprite.png": {"img/*.png"}}); err == nil {
+		t.Error("Expected error on unsupported bundle extension")
+	}
+	s.Bundles(map[string][]string{
+		"css/site.css": {"css/base.css", "css/reset.css"},
+		"/js/app.js":   {"js/*.js"},
+	})
+
+	if !s.IsBundle("/css/site.css") || !s.IsBundle("css/site.css.map") || s.IsBundle("css/reset.css") {
+		t.Error("Unexpected IsBundle result")
+	}
+
+	// Imports are resolved once, in order, and remote imports go first
+	content, sourceMap, err := s.Bundle("css/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "@import url(https://fonts.example.com/font.css);\nhtml {\n  margin: 0;\n}\n:root {\n  --c: red;\n}\nbody {\n  color: red;\n}\n/*# sourceMappingURL=site.css.map */\n"
+	if string(content) != expected {
+		t.Errorf("Expected '%s'. Got '%s'", expected, content)
+	}
+
+	var m struct {
+		Version  int
+		Sources  []string
+		Mappings string
+	}
+	err = json.Unmarshal(sourceMap, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 3 || strings.Join(m.Sources, ",") != "/css/base.css,/css/reset.css,/css/parts/vars.css" {
+		t.Errorf("Unexpected source map: %s", sourceMap)
+	}
+	if lines := len(strings.Split(m.Mappings, ";")); lines != strings.Count(string(content), "\n")-1 {
+		t.Errorf("Expected a mapping for each bundle line. Got %d", lines)
+	}
+
+	// Minified
+	s.Minify(true)
+	content, _, err = s.Bundle("js/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "var a=1;\nvar b=2;\n") {
+		t.Errorf("Expected minified scripts. Got '%s'", content)
+	}
+
+	// Build output and asset URLs
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"css/site.css", "css/site.css.map", "js/app.js", "js/app.js.map"} {
+		if _, err := fs.Stat(out, name); err != nil {
+			t.Errorf("Expected %s in build output: %s", name, err)
+		}
+	}
+	index, err := fs.ReadFile(out, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), `href="/css/site.css?v=`) || !strings.Contains(string(index), `src="/js/app.js?v=`) {
+		t.Errorf("Expected versioned asset URLs. Got '%s'", index)
+	}
+
+	// Missing sources
+	s.Bundles(map[string][]string{"css/missing.css": {"css/none/*.css"}})
+	if _, _, err = s.Bundle("css/missing.css"); err == nil {
+		t.Error("Expected error on bundle without sources")
+	}
+}
@@ -0,0 +1,258 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	// componentsDir is where component templates are looked up by short name.
+	componentsDir string = "components/"
+
+	// slotsProp is the reserved prop name holding the slots passed to a component.
+	slotsProp string = "slots"
+)
+
+// propSpec is a prop declared by a component with the "props" function.
+type propSpec struct {
+	name     string
+	value    string
+	required bool
+	optional bool
+}
+
+// Dict builds a map from a list of key/value pairs, to pass named values to templates and components:
+//
+//  {{ component "card" (dict "title" "Hello" "variant" "primary") }}
+//
+func Dict(values ...interface{}) (map[string]interface{}, error) {
+	if len(values)%2 != 0 {
+		return nil, NewError("Error building dict: odd number of arguments")
+	}
+
+	dict := make(map[string]interface{}, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, NewError(fmt.Sprintf("Error building dict: key %v is not a string", values[i]))
+		}
+		dict[key] = values[i+1]
+	}
+
+	return dict, nil
+}
+
+// Props declares the props of a component and applies their defaults to its data.
+// Each spec is a prop name, followed by "=value" to set a default value or by "?" to make it optional.
+// Props without default value nor "?" are required:
+//
+//  {{ props . "title" "variant=primary" "subtitle?" }}
+//
+// It emits nothing.
+func Props(data map[string]interface{}, specs ...string) (string, error) {
+	for _, spec := range specs {
+		p := parsePropSpec(spec)
+		if _, ok := data[p.name]; ok {
+			continue
+		}
+		if p.required {
+			return "", NewError("Missing required prop '" + p.name + "'")
+		}
+		if !p.optional {
+			data[p.name] = p.value
+		}
+	}
+
+	return "", nil
+}
+
+// parsePropSpec reads a single spec of the "props" function.
+func parsePropSpec(spec string) propSpec {
+	if kv := strings.SplitN(spec, "=", 2); len(kv) == 2 {
+		return propSpec{name: strings.TrimSpace(kv[0]), value: kv[1]}
+	}
+	if strings.HasSuffix(spec, "?") {
+		return propSpec{name: strings.TrimSpace(strings.TrimSuffix(spec, "?")), optional: true}
+	}
+
+	return propSpec{name: strings.TrimSpace(spec), required: true}
+}
+
+// Component renders the named component with the provided props and slots, and returns the output.
+// The name is looked up in the loaded templates as is, and then as "components/<name>.html".
+//
+// Props is a map (see Dict) passed as the component data. Slots is an optional map of named content,
+// usually rendered with Partial, available to the component as .slots.
+// The "default" slot is always set, other slots can be checked with {{ with }}:
+//
+//  {{ define "card-body" }}<p>{{ .Text }}</p>{{ end }}
+//  {{ component "card" (dict "title" "Hello") (dict "default" (partial "card-body" .)) }}
+//
+//  <!-- templates/components/card.html -->
+//  {{ props . "title" "variant=primary" }}
+//  <div class="card card-{{ .variant }}">
+//      <h2>{{ .title }}</h2>
+//      {{ .slots.default }}
+//      {{ with .slots.footer }}<footer>{{ . }}</footer>{{ end }}
+//  </div>
+//
+// Required props declared by the component are validated before rendering,
+// returning a MissingPropError naming the component and the missing prop.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Component(name string, args ...interface{}) (string, error) {
+	s.Lock()
+	tpl := s.tpl
+	s.Unlock()
+
+	return component(tpl, name, args...)
+}
+
+// Partial renders the named template with the provided data and returns the output,
+// i.e. to pass rendered content as component slots.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Partial(name string, data ...interface{}) (string, error) {
+	s.Lock()
+	tpl := s.tpl
+	s.Unlock()
+
+	return partial(tpl, name, data...)
+}
+
+// setFuncs returns the functions bound to the template set executing them and the page it renders,
+// so they can use the templates defined by the page.
+func (s *Service) setFuncs(tpl *template.Template, page string) template.FuncMap {
+	return template.FuncMap{
+		"component": func(name string, args ...interface{}) (string, error) {
+			return component(tpl, name, args...)
+		},
+		"partial": func(name string, data ...interface{}) (string, error) {
+			return partial(tpl, name, data...)
+		},
+		"componentStyles": func() (string, error) {
+			return s.componentStyles(tpl, page)
+		},
+		"componentScripts": func() (string, error) {
+			return s.componentScripts(tpl, page)
+		},
+	}
+}
+
+func component(tpl *template.Template, name string, args ...interface{}) (string, error) {
+	if len(args) > 2 {
+		return "", NewError("Error rendering component " + name + ": too many arguments")
+	}
+
+	// Find component
+	t, err := lookupComponent(tpl, name)
+	if err != nil {
+		return "", err
+	}
+
+	// Component data
+	data := make(map[string]interface{})
+	slots := make(map[string]interface{})
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		m, ok := arg.(map[string]interface{})
+		if !ok {
+			return "", NewError(fmt.Sprintf("Error rendering component %s: argument %d must be a dict, got %T", name, i+1, arg))
+		}
+		if i == 0 {
+			for k, v := range m {
+				data[k] = v
+			}
+		} else {
+			for k, v := range m {
+				slots[k] = v
+			}
+		}
+	}
+	if _, ok := slots["default"]; !ok {
+		slots["default"] = ""
+	}
+	data[slotsProp] = slots
+
+	// Validate props
+	for _, p := range componentProps(t) {
+		if _, ok := data[p.name]; !ok && p.required {
+			return "", NewMissingPropError(name, p.name)
+		}
+	}
+
+	// Render
+	buff := new(bytes.Buffer)
+	err = t.Execute(buff, data)
+	if err != nil {
+		return "", NewError("Error rendering component " + name + ": " + err.Error())
+	}
+
+	return buff.String(), nil
+}
+
+func partial(tpl *template.Template, name string, data ...interface{}) (string, error) {
+	if tpl == nil {
+		return "", NewEmptyTemplateError()
+	}
+
+	t := tpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return "", NewError("Error rendering partial " + name + ": template not found")
+	}
+
+	var dot interface{}
+	if len(data) > 0 {
+		dot = data[0]
+	}
+
+	buff := new(bytes.Buffer)
+	err := t.Execute(buff, dot)
+	if err != nil {
+		return "", NewError("Error rendering partial " + name + ": " + err.Error())
+	}
+
+	return buff.String(), nil
+}
+
+// lookupComponent returns the template of the named component in the set.
+func lookupComponent(tpl *template.Template, name string) (*template.Template, error) {
+	if tpl == nil {
+		return nil, NewEmptyTemplateError()
+	}
+
+	for _, n := range []string{name, componentsDir + name + ".html"} {
+		if t := tpl.Lookup(n); t != nil && t.Tree != nil {
+			return t, nil
+		}
+	}
+
+	return nil, NewError("Error rendering component " + name + ": component not found")
+}
+
+// componentProps returns the props declared by the first "props" call in the component template.
+func componentProps(t *template.Template) []propSpec {
+	var specs []propSpec
+	found := false
+	walkTree(t.Tree.Root, func(node parse.Node) {
+		cmd, ok := node.(*parse.CommandNode)
+		if found || !ok || len(cmd.Args) == 0 {
+			return
+		}
+		if id, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || id.Ident != "props" {
+			return
+		}
+
+		found = true
+		for _, arg := range cmd.Args[1:] {
+			if str, ok := arg.(*parse.StringNode); ok {
+				specs = append(specs, parsePropSpec(str.Text))
+			}
+		}
+	})
+
+	return specs
+}
@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestComponent(t *testing.T) {
+	tplFS := fstest.MapFS{
+		"components/card.html": &fstest.MapFile{Data: []byte(
+			`{{ props . "title" "variant=primary" "subtitle?" }}<div class="{{ .variant }}"><h2>{{ .title }}</h2>{{ with .subtitle }}<h3>{{ . }}</h3>{{ end }}{{ .slots.default }}{{ with .slots.footer }}<footer>{{ . }}</footer>{{ end }}</div>`,
+		)},
+		"components/badge.html": &fstest.MapFile{Data: []byte(`{{ props . "label=new" }}<span>{{ .label }}</span>`)},
+	}
+	s, err := LoadFS(tplFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(src string, data interface{}) (string, error) {
+		buff := new(bytes.Buffer)
+		err := s.RenderString(buff, src, data)
+		return buff.String(), err
+	}
+
+	// Props, defaults and slots
+	out, err := render(`{{ define "body" }}<p>{{ . }}</p>{{ end }}`+
+		`{{ component "card" (dict "title" "Hi") (dict "default" (partial "body" .) "footer" (component "badge")) }}`, "Text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<div class="primary"><h2>Hi</h2><p>Text</p><footer><span>new</span></footer></div>`
+	if out != expected {
+		t.Errorf("Expected '%s'. Got '%s'", expected, out)
+	}
+
+	// Overridden defaults, optional props and full names
+	out, err = render(`{{ component "components/card.html" (dict "title" "Hi" "variant" "dark" "subtitle" "Sub") }}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `<div class="dark"><h2>Hi</h2><h3>Sub</h3></div>`
+	if out != expected {
+		t.Errorf("Expected '%s'. Got '%s'", expected, out)
+	}
+
+	// Missing required prop
+	_, err = s.Component("card", map[string]interface{}{"variant": "dark"})
+	if _, ok := err.(MissingPropError); !ok {
+		t.Fatalf("Expected MissingPropError. Got %v", err)
+	}
+	if err.Error() != "Component card is missing required prop 'title'" {
+		t.Errorf("Unexpected error message: %s", err)
+	}
+	_, err = render(`{{ component "card" }}`, nil)
+	if err == nil || !strings.Contains(err.Error(), "Component card is missing required prop 'title'") {
+		t.Errorf("Expected missing prop error. Got %v", err)
+	}
+
+	// Unknown component and invalid arguments
+	if _, err = render(`{{ component "missing" }}`, nil); err == nil {
+		t.Error("Expected error rendering unknown component")
+	}
+	if _, err = render(`{{ component "card" "title" }}`, nil); err == nil {
+		t.Error("Expected error rendering component with invalid props")
+	}
+	if _, err = render(`{{ dict "title" }}`, nil); err == nil {
+		t.Error("Expected error building dict with odd arguments")
+	}
+}
@@ -0,0 +1,157 @@
+package templates
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported compression encodings, named after their Content-Encoding values.
+const (
+	Gzip   string = "gzip"
+	Brotli string = "br"
+)
+
+// compressExts lists the filename extensions of compressible outputs.
+var compressExts = []string{".html", ".htm", ".css", ".js", ".mjs", ".svg", ".json", ".xml"}
+
+// encodingExts maps encodings to the filename extension of their precompressed siblings.
+var encodingExts = map[string]string{
+	Gzip:   ".gz",
+	Brotli: ".br",
+}
+
+// Compress sets the configuration to write precompressed siblings (i.e. "index.html.gz") of compressible build outputs.
+// Files smaller than minSize bytes are not compressed.
+// A level <= 0 uses the best compression of each encoding, higher levels are capped to the encoding maximum.
+func (s *Service) Compress(encodings []string, level, minSize int) error {
+	encs := make([]string, 0, len(encodings))
+	for _, enc := range encodings {
+		enc = strings.TrimSpace(enc)
+		if enc == "" {
+			continue
+		}
+		if _, ok := encodingExts[enc]; !ok {
+			return NewError("Unsupported compression encoding " + enc)
+		}
+		encs = append(encs, enc)
+	}
+
+	s.encodings = encs
+	s.compressLevel = level
+	s.compressMinSize = minSize
+
+	return nil
+}
+
+// Compressible returns true if the file is of a compressible type (HTML, CSS, JS, SVG, JSON, XML) based on its extension.
+func Compressible(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range compressExts {
+		if e == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Negotiate returns the first encoding from the provided list accepted by an Accept-Encoding header value,
+// preferring the one with the highest quality value.
+// Returns an empty string if none is accepted.
+func Negotiate(acceptEncoding string, encodings []string) string {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range encodings {
+		q, ok := accepted[enc]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+
+	return best
+}
+
+// Encode compresses content with the provided encoding and level into w.
+// A level <= 0 uses the best compression of the encoding.
+func Encode(w io.Writer, encoding string, level int, content []byte) error {
+	var enc io.WriteCloser
+	var err error
+
+	switch encoding {
+	case Gzip:
+		if level <= 0 || level > gzip.BestCompression {
+			level = gzip.BestCompression
+		}
+		enc, err = gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return err
+		}
+
+	case Brotli:
+		if level <= 0 || level > brotli.BestCompression {
+			level = brotli.BestCompression
+		}
+		enc = brotli.NewWriterLevel(w, level)
+
+	default:
+		return NewError("Unsupported compression encoding " + encoding)
+	}
+
+	_, err = enc.Write(content)
+	if err != nil {
+		enc.Close()
+		return err
+	}
+
+	return enc.Close()
+}
+
+// precompress writes the configured compressed siblings of the named build output file.
+func (s *Service) precompress(name string, content []byte) error {
+	if len(s.encodings) == 0 || len(content) < s.compressMinSize || !Compressible(name) {
+		return nil
+	}
+
+	for _, enc := range s.encodings {
+		buff := new(bytes.Buffer)
+		err := Encode(buff, enc, s.compressLevel, content)
+		if err != nil {
+			return err
+		}
+
+		err = s.buildFS.WriteFile(name+encodingExts[enc], buff.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
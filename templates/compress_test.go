@@ -0,0 +1,106 @@
+package templates
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiate(t *testing.T) {
+	encodings := []string{Brotli, Gzip}
+
+	tests := map[string]string{
+		"":                             "",
+		"identity":                     "",
+		"gzip":                         Gzip,
+		"gzip, deflate, br":            Brotli,
+		"gzip;q=1.0, br;q=0.5":         Gzip,
+		"br;q=0, gzip":                 Gzip,
+		"*":                            Brotli,
+		"deflate, GZIP;q=0.8, *;q=0.1": Gzip,
+	}
+
+	for header, expected := range tests {
+		if enc := Negotiate(header, encodings); enc != expected {
+			t.Errorf("Negotiate(%q): expected '%s'. Got '%s'", header, expected, enc)
+		}
+	}
+}
+
+func TestPrecompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := new(Service)
+	s.AddExtension(".html")
+	err = s.Compress([]string{Gzip, Brotli}, 0, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Load("../_example/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "build")
+	err = s.Build("../_example/public", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// gzip
+	gz, err := os.Open(filepath.Join(out, "index.html.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+	gr, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Error("Decoded index.html.gz doesn't match index.html")
+	}
+
+	// brotli
+	br, err := os.Open(filepath.Join(out, "index.html.br"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer br.Close()
+	decoded, err = ioutil.ReadAll(brotli.NewReader(br))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Error("Decoded index.html.br doesn't match index.html")
+	}
+
+	// Not compressible
+	if _, err := os.Stat(filepath.Join(out, "img", "bg-banner.jpg.gz")); err == nil {
+		t.Error("Expected images not to be precompressed")
+	}
+
+	// Unsupported
+	err = s.Compress([]string{"deflate"}, 0, 0)
+	if err == nil {
+		t.Error("Expected error on unsupported encoding")
+	}
+}
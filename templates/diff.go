@@ -0,0 +1,278 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Status of the files compared by CompareFS.
+const (
+	ChangeAdded   = "added"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// diffContext is the number of unchanged lines shown around the changes of a unified diff.
+const diffContext = 3
+
+// Limits of the files compared line by line by UnifiedDiff, bounding its time and memory.
+const (
+	maxDiffLines = 50000
+	maxDiffEdits = 2000
+)
+
+// Change is a file that differs between two build outputs.
+type Change struct {
+	Path   string
+	Status string
+
+	// Content of the file in each build, nil when missing
+	Old []byte
+	New []byte
+}
+
+// CompareFS returns the files added, changed or removed in the build output after, compared to the build output before,
+// sorted by path. A missing before output is compared as empty. The build manifest is left out, as it changes on every build.
+func CompareFS(before, after fs.FS) ([]Change, error) {
+	oldFiles, err := readFiles(before)
+	if err != nil {
+		return nil, NewError("Error reading previous output: " + err.Error())
+	}
+	newFiles, err := readFiles(after)
+	if err != nil {
+		return nil, NewError("Error reading new output: " + err.Error())
+	}
+
+	var changes []Change
+	for name, content := range newFiles {
+		prev, ok := oldFiles[name]
+		if !ok {
+			changes = append(changes, Change{Path: name, Status: ChangeAdded, New: content})
+		} else if !bytes.Equal(prev, content) {
+			changes = append(changes, Change{Path: name, Status: ChangeChanged, Old: prev, New: content})
+		}
+	}
+	for name, content := range oldFiles {
+		if _, ok := newFiles[name]; !ok {
+			changes = append(changes, Change{Path: name, Status: ChangeRemoved, Old: content})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// readFiles returns the content of all files in fsys by name.
+func readFiles(fsys fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if name == "." && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || name == ManifestFile {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[name] = content
+		return nil
+	})
+
+	return files, err
+}
+
+// IsText returns true if the content looks like text: valid UTF-8 without NUL bytes.
+func IsText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// UnifiedDiff returns the differences between the before and after content of the named file in unified diff format,
+// or an empty string when they're equal.
+// Files with too many lines or changes are only reported as different, as "Files a/name and b/name differ".
+func UnifiedDiff(name string, before, after []byte) string {
+	a, b := splitLines(before), splitLines(after)
+	ops, ok := diffLines(a, b)
+	if !ok {
+		return fmt.Sprintf("Files a/%s and b/%s differ\n", name, name)
+	}
+
+	out := new(strings.Builder)
+	for i := 0; i < len(ops); {
+		// Find the next change
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are close enough to share their context
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops) && j <= end+2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		stop := end + diffContext + 1
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(out, "--- a/%s\n+++ b/%s\n", name, name)
+		}
+		writeHunk(out, ops[start:stop])
+		i = stop
+	}
+
+	return out.String()
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+'),
+// with its line number in the old and new content.
+type diffOp struct {
+	kind     byte
+	line     string
+	old, new int
+}
+
+// writeHunk writes the header and lines of a unified diff hunk.
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	oldStart, newStart, oldCount, newCount := ops[0].old, ops[0].new, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits content in lines, keeping their line breaks.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script from a to b, using the Myers diff algorithm,
+// or false when the inputs exceed maxDiffLines or need more than maxDiffEdits edits.
+func diffLines(a, b []string) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	if n > maxDiffLines || m > maxDiffLines {
+		return nil, false
+	}
+	max := n + m
+	if max > maxDiffEdits {
+		max = maxDiffEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// Furthest reaching paths of each edit distance, keeping only the diagonals -d-1 to d+1 reachable at step d
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// backtrack walks the Myers trace back from the end to build the edit script.
+// The trace of step d holds the diagonals -d-1 to d+1.
+func backtrack(a, b []string, trace [][]int, d int) []diffOp {
+	x, y := len(a), len(b)
+	var ops []diffOp
+
+	for ; d >= 0; d-- {
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', line: a[x], old: x, new: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, diffOp{kind: '+', line: b[y], old: x, new: y})
+			} else {
+				x--
+				ops = append(ops, diffOp{kind: '-', line: a[x], old: x, new: y})
+			}
+		}
+	}
+
+	// Reverse
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
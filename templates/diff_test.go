@@ -0,0 +1,95 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompareFS(t *testing.T) {
+	before := fstest.MapFS{
+		"index.html":   &fstest.MapFile{Data: []byte("Home")},
+		"about.html":   &fstest.MapFile{Data: []byte("About")},
+		"old.html":     &fstest.MapFile{Data: []byte("Old")},
+		ManifestFile:   &fstest.MapFile{Data: []byte("{}")},
+		"css/site.css": &fstest.MapFile{Data: []byte("a{}")},
+	}
+	after := fstest.MapFS{
+		"index.html":   &fstest.MapFile{Data: []byte("Home")},
+		"about.html":   &fstest.MapFile{Data: []byte("About us")},
+		"new.html":     &fstest.MapFile{Data: []byte("New")},
+		"css/site.css": &fstest.MapFile{Data: []byte("a{}")},
+	}
+
+	changes, err := CompareFS(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Status+" "+c.Path)
+	}
+	expected := "changed about.html,added new.html,removed old.html"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("Expected changes '%s'. Got '%s'", expected, strings.Join(got, ","))
+	}
+
+	// Missing old output
+	changes, err = CompareFS(fstest.MapFS{}, after)
+	if err != nil || len(changes) != 4 {
+		t.Errorf("Expected all files added. Got %v, %v", changes, err)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn"
+
+	expected := `--- a/x.txt
++++ b/x.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+\ No newline at end of file
+`
+	if diff := UnifiedDiff("x.txt", []byte(before), []byte(after)); diff != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, diff)
+	}
+
+	if diff := UnifiedDiff("x.txt", []byte(before), []byte(before)); diff != "" {
+		t.Errorf("Expected no diff. Got '%s'", diff)
+	}
+	if diff := UnifiedDiff("x.txt", nil, []byte("a\n")); diff != "--- a/x.txt\n+++ b/x.txt\n@@ -0,0 +1,1 @@\n+a\n" {
+		t.Errorf("Unexpected diff of added file '%s'", diff)
+	}
+
+	// Large rewrites are only reported as different
+	var old, rewrite strings.Builder
+	for i := 0; i < 4000; i++ {
+		fmt.Fprintf(&old, "old %d\n", i)
+		fmt.Fprintf(&rewrite, "new %d\n", i)
+	}
+	if diff := UnifiedDiff("x.txt", []byte(old.String()), []byte(rewrite.String())); diff != "Files a/x.txt and b/x.txt differ\n" {
+		t.Errorf("Expected files reported as different. Got %d bytes", len(diff))
+	}
+
+	// Large files with few changes are still compared line by line
+	changed := strings.Replace(old.String(), "old 2000\n", "new 2000\n", 1)
+	if diff := UnifiedDiff("x.txt", []byte(old.String()), []byte(changed)); !strings.Contains(diff, "@@ -1998,7 +1998,7 @@\n") {
+		t.Errorf("Expected a single hunk. Got '%s'", diff)
+	}
+
+	if !IsText([]byte("héllo")) || IsText([]byte("\x89PNG\x00")) {
+		t.Error("Unexpected IsText result")
+	}
+}
@@ -1,7 +1,10 @@
 package templates
 
 import (
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -72,3 +75,76 @@ func NewEmptyTemplateError() EmptyTemplateError {
 		TError: NewError("Empty template. Call templates.Service.Load(): https://godoc.org/github.com/leonelquinteros/thtml/templates#Service.Load"),
 	}
 }
+
+// MissingPropError is returned when a component is rendered without one of its required props.
+type MissingPropError struct {
+	// Error composition
+	TError
+
+	Component string
+	Prop      string
+}
+
+// NewMissingPropError returns a new MissingPropError object
+func NewMissingPropError(component, prop string) MissingPropError {
+	return MissingPropError{
+		TError:    NewError("Component " + component + " is missing required prop '" + prop + "'"),
+		Component: component,
+		Prop:      prop,
+	}
+}
+
+// errorLocation matches the "file:line:column:" location of template and compilation errors.
+var errorLocation = regexp.MustCompile(`([^\s:"]+\.\w+):(\d+)(?::(\d+))?:`)
+
+// BuildError is a file of the public file system that failed to build.
+type BuildError struct {
+	// Error composition
+	TError `json:"-"`
+
+	// Public file being built
+	Page string `json:"page"`
+
+	// File, line and column where the error happened, when known.
+	// File is a template used by Page for errors in layouts and components.
+	File   string `json:"file"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// NewBuildError returns a new BuildError object for the error building page,
+// locating it from its message.
+func NewBuildError(page string, err error) BuildError {
+	e := BuildError{
+		TError:  NewError(err.Error()),
+		Page:    page,
+		File:    page,
+		Message: err.Error(),
+	}
+
+	if m := errorLocation.FindStringSubmatch(err.Error()); m != nil {
+		e.File = strings.TrimPrefix(m[1], "/")
+		e.Line, _ = strconv.Atoi(m[2])
+		e.Column, _ = strconv.Atoi(m[3])
+	}
+
+	return e
+}
+
+// BuildErrors is returned by builds that keep going after errors, with the errors of all failed files.
+type BuildErrors struct {
+	// Error composition
+	TError
+
+	Errors []BuildError
+}
+
+// NewBuildErrors returns a new BuildErrors object
+func NewBuildErrors(errs []BuildError) BuildErrors {
+	return BuildErrors{
+		TError: NewError(strconv.Itoa(len(errs)) + " files failed to build"),
+		Errors: errs,
+	}
+}
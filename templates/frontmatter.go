@@ -0,0 +1,199 @@
+package templates
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// Publishing status of pages, set by their front matter.
+const (
+	StatusDraft   = "draft"
+	StatusFuture  = "future"
+	StatusExpired = "expired"
+)
+
+// frontMatterDelim opens and closes the front matter block of a page.
+const frontMatterDelim = "---"
+
+// dateLayouts are the accepted formats of front matter dates.
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// FrontMatter is the metadata of a page, set in a block of "key: value" lines between "---" lines at its start:
+//
+//  ---
+//  title: About us
+//  draft: true
+//  publishDate: 2024-05-01
+//  expiryDate: 2025-05-01T12:00:00Z
+//  ---
+//  {{ template "layout.html" }}
+//
+// Pages access their front matter with the frontMatter template function: {{ (frontMatter).title }}
+type FrontMatter map[string]string
+
+// SkippedPage is a page left out of the build by its publishing status.
+type SkippedPage struct {
+	Name   string
+	Status string
+}
+
+// ParseFrontMatter splits the front matter from the content of a page.
+// The front matter is replaced by a template comment spanning the same lines,
+// so errors in the rest of the page keep pointing to their line in the file.
+// Content without front matter is returned as it is.
+func ParseFrontMatter(content []byte) (FrontMatter, []byte, error) {
+	matter := make(FrontMatter)
+
+	src := string(content)
+	if !strings.HasPrefix(src, frontMatterDelim+"\n") && !strings.HasPrefix(src, frontMatterDelim+"\r\n") {
+		return matter, content, nil
+	}
+
+	lines := strings.SplitAfter(src, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == frontMatterDelim {
+			// Keep line numbers of the page content
+			newlines := strings.Count(strings.Join(lines[:i+1], ""), "\n")
+			body := "{{/*" + strings.Repeat("\n", newlines) + "*/}}" + strings.Join(lines[i+1:], "")
+			return matter, []byte(body), nil
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sep := strings.Index(line, ":")
+		if sep <= 0 {
+			return nil, nil, NewError("Error parsing front matter line " + line + ": expected key: value")
+		}
+		key := strings.TrimSpace(line[:sep])
+		value := strings.TrimSpace(line[sep+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		matter[key] = value
+	}
+
+	return nil, nil, NewError("Error parsing front matter: missing closing " + frontMatterDelim)
+}
+
+// Status returns the publishing status of the page at the provided time:
+// StatusDraft when "draft" is true, StatusFuture before its "publishDate",
+// StatusExpired after its "expiryDate", or an empty string when the page is published.
+func (m FrontMatter) Status(now time.Time) (string, error) {
+	if strings.EqualFold(m["draft"], "true") {
+		return StatusDraft, nil
+	}
+
+	for _, key := range []string{"publishDate", "expiryDate"} {
+		if m[key] == "" {
+			continue
+		}
+		date, err := parseDate(m[key])
+		if err != nil {
+			return "", NewError("Error parsing front matter " + key + ": " + err.Error())
+		}
+		if key == "publishDate" && now.Before(date) {
+			return StatusFuture, nil
+		}
+		if key == "expiryDate" && !now.Before(date) {
+			return StatusExpired, nil
+		}
+	}
+
+	return "", nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var t time.Time
+		t, err = time.ParseInLocation(layout, v, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Drafts sets the configuration to build pages marked as drafts in their front matter.
+func (s *Service) Drafts(d bool) {
+	s.drafts = d
+}
+
+// Future sets the configuration to build pages with a publish date in the future.
+func (s *Service) Future(f bool) {
+	s.future = f
+}
+
+// Expired sets the configuration to build pages with an expiry date in the past.
+func (s *Service) Expired(e bool) {
+	s.expired = e
+}
+
+// PageStatus returns the publishing status of the named page of the public file system,
+// according to its front matter, or an empty string when it's published.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) PageStatus(name string) (string, error) {
+	name = path.Clean("/" + name)[1:]
+	if !s.ValidExtension(path.Ext(name)) {
+		return "", nil
+	}
+	if s.publicFS == nil {
+		return "", NewError("Error reading template " + name + ": public file system not set")
+	}
+
+	content, err := fs.ReadFile(s.publicFS, name)
+	if err != nil {
+		return "", NewError("Error reading template " + name + ": " + err.Error())
+	}
+	if !bytes.HasPrefix(content, []byte(frontMatterDelim)) {
+		return "", nil
+	}
+
+	matter, _, err := ParseFrontMatter(content)
+	if err != nil {
+		return "", NewError("Error parsing template " + name + ": " + err.Error())
+	}
+
+	return matter.Status(time.Now())
+}
+
+// Skipped returns the pages left out of the last build by their publishing status.
+func (s *Service) Skipped() []SkippedPage {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]SkippedPage(nil), s.skipped...)
+}
+
+// included returns true if pages with the publishing status are built.
+func (s *Service) included(status string) bool {
+	return status == "" ||
+		(status == StatusDraft && s.drafts) ||
+		(status == StatusFuture && s.future) ||
+		(status == StatusExpired && s.expired)
+}
+
+// publishable returns false for the pages to leave out of the build by their publishing status,
+// and records them as skipped.
+func (s *Service) publishable(name string) (bool, error) {
+	status, err := s.PageStatus(name)
+	if err != nil {
+		return false, err
+	}
+
+	if s.included(status) {
+		return true, nil
+	}
+
+	s.Lock()
+	s.skipped = append(s.skipped, SkippedPage{Name: name, Status: status})
+	s.Unlock()
+	s.report.warn("Skipped " + status + " page " + name)
+
+	return false, nil
+}
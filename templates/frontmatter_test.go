@@ -0,0 +1,129 @@
+package templates
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	matter, body, err := ParseFrontMatter([]byte("---\ntitle: \"About: us\"\n# comment\ndraft: true\n---\n<h1>{{ (frontMatter).title }}</h1>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matter["title"] != "About: us" || matter["draft"] != "true" || len(matter) != 2 {
+		t.Errorf("Unexpected front matter %v", matter)
+	}
+	if string(body) != "{{/*\n\n\n\n\n*/}}<h1>{{ (frontMatter).title }}</h1>\n" {
+		t.Errorf("Unexpected page body '%s'", body)
+	}
+
+	// No front matter
+	_, body, err = ParseFrontMatter([]byte("<p>---</p>"))
+	if err != nil || string(body) != "<p>---</p>" {
+		t.Errorf("Expected content as it is. Got '%s', %v", body, err)
+	}
+
+	// Errors
+	for _, src := range []string{"---\ntitle: x\n", "---\ntitle\n---\n"} {
+		if _, _, err = ParseFrontMatter([]byte(src)); err == nil {
+			t.Errorf("Expected error parsing '%s'", src)
+		}
+	}
+}
+
+func TestFrontMatterStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+
+	tests := map[string]FrontMatter{
+		"":            {"title": "Published", "publishDate": "2024-05-01", "expiryDate": "2024-07-01T00:00:00Z"},
+		StatusDraft:   {"draft": "true", "publishDate": "2030-01-01"},
+		StatusFuture:  {"publishDate": "2024-06-01 13:00"},
+		StatusExpired: {"expiryDate": "2024-06-01"},
+	}
+	for expected, matter := range tests {
+		status, err := matter.Status(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != expected {
+			t.Errorf("Expected status '%s' for %v. Got '%s'", expected, matter, status)
+		}
+	}
+
+	if _, err := (FrontMatter{"publishDate": "tomorrow"}).Status(now); err == nil {
+		t.Error("Expected error on invalid date")
+	}
+}
+
+func TestBuildPublishing(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"index.html":   &fstest.MapFile{Data: []byte("---\ntitle: Home\n---\n<h1>{{ (frontMatter).title }}</h1>")},
+		"draft.html":   &fstest.MapFile{Data: []byte("---\ndraft: true\n---\nDraft")},
+		"future.html":  &fstest.MapFile{Data: []byte("---\npublishDate: 2999-01-01\n---\nFuture")},
+		"expired.html": &fstest.MapFile{Data: []byte("---\nexpiryDate: 2000-01-01\n---\nExpired")},
+		"broken.html":  &fstest.MapFile{Data: []byte("---\ntitle: Broken\n---\n\n{{ .Missing.Field }")},
+	}
+
+	s, err := LoadFS(fstest.MapFS{
+		"title.html": &fstest.MapFile{Data: []byte(`{{ with frontMatter }}{{ .title }}{{ end }}`)},
+	}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PublicFS(publicFS)
+
+	// Line numbers of errors are kept
+	err = s.RenderFile(new(bytes.Buffer), "broken.html", nil)
+	if err == nil || !strings.Contains(err.Error(), "broken.html:5") {
+		t.Errorf("Expected error at line 5. Got '%v'", err)
+	}
+	delete(publicFS, "broken.html")
+
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := fs.ReadFile(out, "index.html")
+	if err != nil || string(index) != "<h1>Home</h1>" {
+		t.Errorf("Expected page rendered without front matter. Got '%s', %v", index, err)
+	}
+	for _, name := range []string{"draft.html", "future.html", "expired.html"} {
+		if _, err := fs.Stat(out, name); err == nil {
+			t.Errorf("Unexpected %s in build output", name)
+		}
+	}
+	skipped := s.Skipped()
+	if len(skipped) != 3 || skipped[0] != (SkippedPage{Name: "draft.html", Status: StatusDraft}) {
+		t.Errorf("Unexpected skipped pages %v", skipped)
+	}
+
+	// Include all
+	s.Drafts(true)
+	s.Future(true)
+	s.Expired(true)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"draft.html", "future.html", "expired.html"} {
+		if _, err := fs.Stat(out, name); err != nil {
+			t.Errorf("Expected %s in build output: %s", name, err)
+		}
+	}
+	if len(s.Skipped()) != 0 {
+		t.Errorf("Unexpected skipped pages %v", s.Skipped())
+	}
+
+	// Templates see the front matter of the page
+	publicFS["title.html"] = &fstest.MapFile{Data: []byte("---\ntitle: Nested\n---\n{{ template \"title.html\" }}")}
+	buff := new(bytes.Buffer)
+	err = s.RenderFile(buff, "title.html", nil)
+	if err != nil || buff.String() != "Nested" {
+		t.Errorf("Expected front matter in templates. Got '%s', %v", buff.String(), err)
+	}
+}
@@ -0,0 +1,309 @@
+package templates
+
+import (
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// WriteFS is a file system that can be written to. It's used as the build output.
+// Names follow the fs.FS conventions: slash-separated, unrooted paths.
+type WriteFS interface {
+	fs.FS
+
+	// MkdirAll creates a directory named name, along with any necessary parents.
+	MkdirAll(name string, perm fs.FileMode) error
+
+	// WriteFile writes data to the named file, creating it if necessary.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	// RemoveAll removes name and any children it contains.
+	RemoveAll(name string) error
+}
+
+// dirFS is a WriteFS rooted at a directory on disk.
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+// DirFS returns a WriteFS for the tree of files rooted at the directory dir.
+func DirFS(dir string) WriteFS {
+	return &dirFS{
+		FS:  os.DirFS(dir),
+		dir: dir,
+	}
+}
+
+func (d *dirFS) path(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return filepath.Join(d.dir, filepath.FromSlash(name)), nil
+}
+
+// MkdirAll implements WriteFS
+func (d *dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	fn, err := d.path(name)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(fn, perm)
+}
+
+// WriteFile implements WriteFS
+func (d *dirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	fn, err := d.path(name)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fn, data, perm)
+}
+
+// RemoveAll implements WriteFS
+func (d *dirFS) RemoveAll(name string) error {
+	fn, err := d.path(name)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(fn)
+}
+
+// MemFS is an in-memory WriteFS, useful to render builds without touching the disk.
+// The zero value is ready to use.
+// This type is safe to use from multiple/concurrent goroutines.
+type MemFS struct {
+	sync.Mutex
+
+	files fstest.MapFS
+}
+
+// Open implements fs.FS
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.Lock()
+
+	// Files are replaced on write, so they're opened on their own
+	if f, ok := m.files[name]; ok && !f.Mode.IsDir() {
+		m.Unlock()
+		return fstest.MapFS{name: f}.Open(name)
+	}
+
+	// Directories list a snapshot of the files
+	snapshot := make(fstest.MapFS, len(m.files))
+	for k, v := range m.files {
+		snapshot[k] = v
+	}
+	m.Unlock()
+
+	return snapshot.Open(name)
+}
+
+// MkdirAll implements WriteFS
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if m.files == nil {
+		m.files = make(fstest.MapFS)
+	}
+	for p := name; p != "."; p = path.Dir(p) {
+		if f, ok := m.files[p]; ok && !f.Mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		if _, ok := m.files[p]; !ok {
+			m.files[p] = &fstest.MapFile{Mode: fs.ModeDir | perm, ModTime: time.Now()}
+		}
+	}
+
+	return nil
+}
+
+// WriteFile implements WriteFS
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if m.files == nil {
+		m.files = make(fstest.MapFS)
+	}
+	if f, ok := m.files[name]; ok && f.Mode.IsDir() {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+
+	content := make([]byte, len(data))
+	copy(content, data)
+	m.files[name] = &fstest.MapFile{Data: content, Mode: perm, ModTime: time.Now()}
+
+	return nil
+}
+
+// RemoveAll implements WriteFS
+func (m *MemFS) RemoveAll(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	for k := range m.files {
+		if name == "." || k == name || strings.HasPrefix(k, name+"/") {
+			delete(m.files, k)
+		}
+	}
+
+	return nil
+}
+
+// overlayFS is a read-only file system merging multiple layers.
+type overlayFS []fs.FS
+
+// OverlayFS returns a read-only file system merging the provided layers,
+// i.e. the public assets of a theme under the project's public directory.
+// Files in later layers replace the ones with the same name in earlier layers,
+// and directories list the files of all layers.
+func OverlayFS(layers ...fs.FS) fs.FS {
+	return overlayFS(layers)
+}
+
+// Open implements fs.FS
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	for i := len(o) - 1; i >= 0; i-- {
+		info, err := fs.Stat(o[i], name)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			return o[i].Open(name)
+		}
+
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &overlayDir{info: info, entries: entries}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	merged := make(map[string]fs.DirEntry)
+	found := false
+	for i := len(o) - 1; i >= 0; i-- {
+		// Files in later layers hide directories with the same name in earlier ones
+		info, err := fs.Stat(o[i], name)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			break
+		}
+
+		entries, err := fs.ReadDir(o[i], name)
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range entries {
+			if _, ok := merged[e.Name()]; !ok {
+				merged[e.Name()] = e
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, e := range merged {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// overlayDir is an open directory of an overlayFS.
+type overlayDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+// Stat implements fs.File
+func (d *overlayDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read implements fs.File
+func (d *overlayDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// Close implements fs.File
+func (d *overlayDir) Close() error {
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+
+	return rest[:n], nil
+}
+
+// writeFile writes data to the named file of fsys, creating the parent directories.
+func writeFile(fsys WriteFS, name string, data []byte) error {
+	err := fsys.MkdirAll(path.Dir(name), 0755)
+	if err != nil {
+		return err
+	}
+
+	return fsys.WriteFile(name, data, 0644)
+}
@@ -0,0 +1,188 @@
+package templates
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBuildFS(t *testing.T) {
+	tplFS := fstest.MapFS{
+		"layouts/default.html": &fstest.MapFile{Data: []byte(`<h1>{{ block "title" . }}{{ end }}</h1>`)},
+		"components/skip.txt":  &fstest.MapFile{Data: []byte(`{{ invalid`)},
+	}
+	publicFS := fstest.MapFS{
+		"index.html":       &fstest.MapFile{Data: []byte(`{{ template "layouts/default.html" }}{{ define "title" }}Home{{ end }}`)},
+		"about/index.html": &fstest.MapFile{Data: []byte(`{{ template "layouts/default.html" }}{{ define "title" }}About{{ end }}`)},
+		"css/main.css":     &fstest.MapFile{Data: []byte(`body { color: red; }`)},
+	}
+
+	s, err := LoadFS(tplFS, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(MemFS)
+	err = out.WriteFile("stale.html", []byte("stale"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"index.html":       "<h1>Home</h1>",
+		"about/index.html": "<h1>About</h1>",
+		"css/main.css":     "body { color: red; }",
+	}
+	for name, content := range expected {
+		got, err := fs.ReadFile(out, name)
+		if err != nil {
+			t.Errorf("Expected %s in build output: %s", name, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("Expected %s content '%s'. Got '%s'", name, content, got)
+		}
+	}
+
+	if _, err := fs.Stat(out, "stale.html"); err == nil {
+		t.Error("Expected output to be cleaned before build")
+	}
+
+	// RenderFile uses the public file system set by BuildFS
+	buff := new(bytes.Buffer)
+	err = s.RenderFile(buff, "about/index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buff.String() != expected["about/index.html"] {
+		t.Errorf("Expected '%s'. Got '%s'", expected["about/index.html"], buff.String())
+	}
+}
+
+func TestBuildKeepGoing(t *testing.T) {
+	tplFS := fstest.MapFS{
+		"layouts/default.html": &fstest.MapFile{Data: []byte("<h1>\n{{ index . 1 }}</h1>")},
+	}
+	publicFS := fstest.MapFS{
+		"index.html":  &fstest.MapFile{Data: []byte(`{{ template "layouts/default.html" . }}`)},
+		"broken.html": &fstest.MapFile{Data: []byte("ok\n\n{{ if }}")},
+		"about.html":  &fstest.MapFile{Data: []byte(`About`)},
+	}
+
+	s, err := LoadFS(tplFS, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fail fast
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if _, ok := err.(BuildErrors); err == nil || ok {
+		t.Errorf("Expected first error. Got %v", err)
+	}
+
+	// Keep going
+	s.KeepGoing(true)
+	err = s.BuildFS(publicFS, out)
+	errs, ok := err.(BuildErrors)
+	if !ok {
+		t.Fatalf("Expected BuildErrors. Got %v", err)
+	}
+	if len(errs.Errors) != 2 {
+		t.Fatalf("Expected 2 errors. Got %v", errs.Errors)
+	}
+	if e := errs.Errors[0]; e.Page != "broken.html" || e.File != "broken.html" || e.Line != 3 {
+		t.Errorf("Expected error at broken.html:3. Got %+v", e)
+	}
+	if e := errs.Errors[1]; e.Page != "index.html" || e.File != "layouts/default.html" || e.Line != 2 {
+		t.Errorf("Expected error of index.html at layouts/default.html:2. Got %+v", e)
+	}
+	if _, err := fs.Stat(out, "about.html"); err != nil {
+		t.Errorf("Expected about.html built after errors: %s", err)
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	m := new(MemFS)
+
+	err := m.MkdirAll("a/b", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.WriteFile("a/b/c.txt", []byte("c"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.WriteFile("a/d.txt", []byte("d"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fstest.TestFS(m, "a/b/c.txt", "a/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.RemoveAll("a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(m, "a/b/c.txt"); err == nil {
+		t.Error("Expected a/b/c.txt to be removed")
+	}
+	if _, err := fs.Stat(m, "a/d.txt"); err != nil {
+		t.Errorf("Expected a/d.txt to exist: %s", err)
+	}
+
+	err = m.WriteFile("../outside.txt", nil, 0644)
+	if err == nil {
+		t.Error("Expected error on invalid path")
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	theme := fstest.MapFS{
+		"index.html":    &fstest.MapFile{Data: []byte("theme index")},
+		"css/theme.css": &fstest.MapFile{Data: []byte("theme css")},
+		"css/site.css":  &fstest.MapFile{Data: []byte("theme site")},
+		"img/logo.svg":  &fstest.MapFile{Data: []byte("<svg/>")},
+		"about":         &fstest.MapFile{Data: []byte("theme file")},
+	}
+	project := fstest.MapFS{
+		"index.html":       &fstest.MapFile{Data: []byte("project index")},
+		"css/site.css":     &fstest.MapFile{Data: []byte("project site")},
+		"about/index.html": &fstest.MapFile{Data: []byte("project about")},
+	}
+	o := OverlayFS(theme, project)
+
+	err := fstest.TestFS(o, "index.html", "css/theme.css", "css/site.css", "img/logo.svg", "about/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"index.html":       "project index",
+		"css/theme.css":    "theme css",
+		"css/site.css":     "project site",
+		"about/index.html": "project about",
+	}
+	for name, content := range expected {
+		buff, err := fs.ReadFile(o, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buff) != content {
+			t.Errorf("Expected '%s' in %s. Got '%s'", content, name, string(buff))
+		}
+	}
+
+	if _, err := o.Open("missing.html"); err == nil {
+		t.Error("Expected error opening missing file")
+	}
+}
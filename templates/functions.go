@@ -10,6 +10,8 @@ import (
 var FuncMap = template.FuncMap{
 	"ID":      ID,
 	"BuildID": BuildID,
+	"dict":    Dict,
+	"props":   Props,
 }
 
 // ID returns a random [8]byte value encoded as hex string (16).
@@ -0,0 +1,297 @@
+package templates
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// Kinds of template graph nodes.
+const (
+	NodePage     = "page"
+	NodeTemplate = "template"
+	NodeDefine   = "define"
+)
+
+// Kinds of template graph edges.
+const (
+	EdgeInclude   = "include"
+	EdgeComponent = "component"
+	EdgePartial   = "partial"
+	EdgeDefine    = "define"
+	EdgeBlock     = "block"
+)
+
+// Graph is the dependency graph of templates and pages.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a page, a template file or a define block.
+// Pages are named after their public path with a leading slash (i.e. "/about/index.html"),
+// so they don't clash with template names.
+type GraphNode struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+
+	// Template files defining the block, for define nodes
+	Files []string `json:"files,omitempty"`
+
+	// True for template files without content besides their define blocks
+	Empty bool `json:"empty,omitempty"`
+}
+
+// GraphEdge is a relationship between two nodes of the graph:
+// the From node includes the To template, renders it as component or partial, defines it, or defines and calls it as block.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// Graph parses all templates in the provided roots, or in the loaded roots when none are provided,
+// and all pages of the public file system, without executing them, and returns their dependency graph.
+// Components and partials are only followed when rendered with a constant name.
+func (s *Service) Graph(roots ...fs.FS) (*Graph, error) {
+	if len(roots) == 0 {
+		s.Lock()
+		roots = s.tplRoots
+		s.Unlock()
+	}
+
+	files, err := s.parseSources(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*GraphNode)
+	addNode := func(name, kind string) *GraphNode {
+		if n, ok := nodes[name]; ok {
+			return n
+		}
+		nodes[name] = &GraphNode{Name: name, Kind: kind}
+		return nodes[name]
+	}
+
+	// Definitions
+	defined := make(map[string]bool)
+	for _, f := range files {
+		if f.err != nil {
+			return nil, NewError("Error parsing template " + f.name + ": " + f.err.Error())
+		}
+		if f.page {
+			addNode("/"+f.name, NodePage)
+			continue
+		}
+
+		addNode(f.name, NodeTemplate).Empty = isEmptyTemplate(f.trees[f.name])
+		defined[f.name] = true
+		for name := range f.trees {
+			if name != f.name {
+				defined[name] = true
+			}
+		}
+	}
+
+	edges := make(map[GraphEdge]bool)
+	for _, f := range files {
+		from := func(tree string) string {
+			if f.page {
+				return "/" + f.name
+			}
+			return tree
+		}
+
+		// References
+		called := make(map[string]bool)
+		for _, ref := range f.refs {
+			for _, name := range ref.names {
+				if !defined[name] && (!f.page || f.trees[name] == nil) {
+					continue
+				}
+
+				// Calls to a define block of the same file are blocks
+				kind := ref.kind
+				if kind == "template" {
+					kind = EdgeInclude
+				}
+				if f.trees[name] != nil && name != f.name {
+					called[name] = true
+					kind = EdgeBlock
+				}
+				edges[GraphEdge{From: from(ref.from), To: name, Kind: kind}] = true
+				break
+			}
+		}
+
+		// Define blocks
+		for _, name := range sortedTrees(f) {
+			if name == f.name {
+				continue
+			}
+			n := addNode(name, NodeDefine)
+			if !f.page {
+				n.Files = append(n.Files, f.name)
+			}
+			if !called[name] {
+				edges[GraphEdge{From: from(f.name), To: name, Kind: EdgeDefine}] = true
+			}
+		}
+	}
+
+	g := new(Graph)
+	for e := range edges {
+		g.Edges = append(g.Edges, e)
+	}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Name < g.Nodes[j].Name
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+
+	return g, nil
+}
+
+// Has returns true if the graph has a node named name, like a template file or a define block.
+func (g *Graph) Has(name string) bool {
+	for _, n := range g.Nodes {
+		if n.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UsedBy returns the public paths of the pages using the named template or define block,
+// directly or through other templates, sorted.
+func (g *Graph) UsedBy(name string) []string {
+	callers := make(map[string][]string)
+	for _, e := range g.Edges {
+		if e.Kind != EdgeDefine {
+			callers[e.To] = append(callers[e.To], e.From)
+		}
+	}
+
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	var pages []string
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, c := range callers[n] {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			if strings.HasPrefix(c, "/") {
+				pages = append(pages, c[1:])
+			}
+			queue = append(queue, c)
+		}
+	}
+	sort.Strings(pages)
+
+	return pages
+}
+
+// Orphans returns the templates and define blocks never included, rendered or called by other templates or pages, sorted.
+// Template files only holding define blocks aren't orphans, as they aren't meant to be included.
+func (g *Graph) Orphans() []string {
+	used := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.Kind != EdgeDefine {
+			used[e.To] = true
+		}
+	}
+
+	var orphans []string
+	for _, n := range g.Nodes {
+		if n.Kind == NodePage || used[n.Name] {
+			continue
+		}
+		if n.Kind == NodeTemplate && n.Empty {
+			continue
+		}
+		orphans = append(orphans, n.Name)
+	}
+
+	return orphans
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+// Pages are drawn as boxes, define blocks as ellipses and template files as notes.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	shapes := map[string]string{NodePage: "box", NodeTemplate: "note", NodeDefine: "ellipse"}
+
+	b := new(strings.Builder)
+	b.WriteString("digraph templates {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(b, "  %s [shape=%s];\n", strconv.Quote(n.Name), shapes[n.Kind])
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "  %s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if e.Kind != EdgeInclude {
+			fmt.Fprintf(b, " [label=%s", strconv.Quote(e.Kind))
+			if e.Kind == EdgeDefine {
+				b.WriteString(", style=dashed")
+			}
+			b.WriteString("]")
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+// Pages are drawn as rectangles, define blocks as rounded rectangles and template files as subroutines.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	shapes := map[string][2]string{NodePage: {"[", "]"}, NodeTemplate: {"[[", "]]"}, NodeDefine: {"(", ")"}}
+
+	ids := make(map[string]string, len(g.Nodes))
+	b := new(strings.Builder)
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		ids[n.Name] = "n" + strconv.Itoa(i)
+		shape := shapes[n.Kind]
+		fmt.Fprintf(b, "  %s%s\"%s\"%s\n", ids[n.Name], shape[0], strings.ReplaceAll(n.Name, `"`, "#quot;"), shape[1])
+	}
+	for _, e := range g.Edges {
+		switch e.Kind {
+		case EdgeInclude:
+			fmt.Fprintf(b, "  %s --> %s\n", ids[e.From], ids[e.To])
+		case EdgeDefine:
+			fmt.Fprintf(b, "  %s -.->|define| %s\n", ids[e.From], ids[e.To])
+		default:
+			fmt.Fprintf(b, "  %s -->|%s| %s\n", ids[e.From], e.Kind, ids[e.To])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// isEmptyTemplate returns true if the tree has no content.
+func isEmptyTemplate(tree *parse.Tree) bool {
+	return tree == nil || parse.IsEmptyTree(tree.Root)
+}
@@ -0,0 +1,97 @@
+package templates
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGraph(t *testing.T) {
+	tplFS := fstest.MapFS{
+		"layouts/default.html": &fstest.MapFile{Data: []byte(`{{ block "header" . }}{{ component "nav" }}{{ end }}{{ block "title" . }}{{ end }}{{ template "footer" }}`)},
+		"components/nav.html":  &fstest.MapFile{Data: []byte(`<nav></nav>`)},
+		"partials.html":        &fstest.MapFile{Data: []byte(`{{ define "footer" }}Footer{{ end }}{{ define "unused" }}{{ partial "old.html" }}{{ end }}`)},
+		"old.html":             &fstest.MapFile{Data: []byte(`Old`)},
+		"orphan.html":          &fstest.MapFile{Data: []byte(`Orphan`)},
+	}
+	publicFS := fstest.MapFS{
+		"index.html":    &fstest.MapFile{Data: []byte(`{{ template "layouts/default.html" }}{{ define "title" }}Home{{ end }}`)},
+		"about.html":    &fstest.MapFile{Data: []byte(`{{ template "footer" }}`)},
+		"contact.html":  &fstest.MapFile{Data: []byte(`Contact`)},
+		"css/site.css":  &fstest.MapFile{Data: []byte(`body {}`)},
+		"_partial.html": &fstest.MapFile{Data: []byte(`{{ template "orphan.html" }}`)},
+	}
+
+	s := New(".html")
+	s.PublicFS(publicFS)
+	g, err := s.Graph(tplFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []GraphEdge{
+		{From: "/about.html", To: "footer", Kind: EdgeInclude},
+		{From: "/index.html", To: "layouts/default.html", Kind: EdgeInclude},
+		{From: "/index.html", To: "title", Kind: EdgeDefine},
+		{From: "header", To: "components/nav.html", Kind: EdgeComponent},
+		{From: "layouts/default.html", To: "footer", Kind: EdgeInclude},
+		{From: "layouts/default.html", To: "header", Kind: EdgeBlock},
+		{From: "layouts/default.html", To: "title", Kind: EdgeBlock},
+		{From: "partials.html", To: "footer", Kind: EdgeDefine},
+		{From: "partials.html", To: "unused", Kind: EdgeDefine},
+		{From: "unused", To: "old.html", Kind: EdgePartial},
+	}
+	if len(g.Edges) != len(expected) {
+		t.Fatalf("Expected %d edges. Got %v", len(expected), g.Edges)
+	}
+	for i := range expected {
+		if g.Edges[i] != expected[i] {
+			t.Errorf("Expected edge %v. Got %v", expected[i], g.Edges[i])
+		}
+	}
+
+	used := g.UsedBy("components/nav.html")
+	if strings.Join(used, ",") != "index.html" {
+		t.Errorf("Expected components/nav.html used by index.html. Got %v", used)
+	}
+	used = g.UsedBy("footer")
+	if strings.Join(used, ",") != "about.html,index.html" {
+		t.Errorf("Expected footer used by about.html and index.html. Got %v", used)
+	}
+
+	if !g.Has("footer") || g.Has("components/typo.html") {
+		t.Error("Expected graph to have footer and not components/typo.html")
+	}
+
+	orphans := g.Orphans()
+	if strings.Join(orphans, ",") != "orphan.html,unused" {
+		t.Errorf("Expected orphans orphan.html and unused. Got %v", orphans)
+	}
+
+	// Formats
+	buff := new(bytes.Buffer)
+	g.WriteDOT(buff)
+	for _, line := range []string{
+		`"/index.html" [shape=box];`,
+		`"/index.html" -> "layouts/default.html";`,
+		`"partials.html" -> "footer" [label="define", style=dashed];`,
+	} {
+		if !strings.Contains(buff.String(), line) {
+			t.Errorf("Expected DOT output to contain '%s'. Got:\n%s", line, buff.String())
+		}
+	}
+
+	buff.Reset()
+	g.WriteMermaid(buff)
+	for _, line := range []string{
+		"flowchart LR\n",
+		`n0["/about.html"]`,
+		"n0 --> n",
+		"-->|component|",
+	} {
+		if !strings.Contains(buff.String(), line) {
+			t.Errorf("Expected Mermaid output to contain '%s'. Got:\n%s", line, buff.String())
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package templates
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile is the name of the file listing the ignore rules of a project.
+const IgnoreFile = ".thtmlignore"
+
+// IgnoreRules matches file paths against a list of patterns in gitignore syntax:
+//
+//  # Names without a slash match at any level
+//  .DS_Store
+//  *.sw?
+//  # A leading slash anchors the pattern to the root, a trailing one only matches directories
+//  /drafts/
+//  # "**" matches any number of directories
+//  src/**/*.psd
+//  # A leading "!" includes again the paths matched by previous patterns
+//  !keep.psd
+//
+// Blank lines and lines starting with "#" are skipped.
+// Paths are relative to the root of the file system being walked, like the public or templates directories.
+// The last matching pattern wins, and files inside ignored directories are always ignored.
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	re     *regexp.Regexp
+	negate bool
+	dir    bool
+}
+
+// ParseIgnore returns the ignore rules of the content of an ignore file, one pattern per line.
+func ParseIgnore(src string) (*IgnoreRules, error) {
+	return NewIgnoreRules(strings.Split(src, "\n")...)
+}
+
+// NewIgnoreRules returns the ignore rules of the provided patterns.
+func NewIgnoreRules(patterns ...string) (*IgnoreRules, error) {
+	r := new(IgnoreRules)
+	err := r.Add(patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Add appends patterns to the rules, taking precedence over the existent ones.
+func (r *IgnoreRules) Add(patterns ...string) error {
+	for _, p := range patterns {
+		p = strings.TrimRight(p, " \t\r")
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(p, "!") {
+			rule.negate = true
+			p = p[1:]
+		}
+		if strings.HasPrefix(p, `\`) {
+			p = p[1:]
+		}
+		if strings.HasSuffix(p, "/") {
+			rule.dir = true
+			p = strings.TrimRight(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+
+		// Patterns with a slash are relative to the root, others match at any level
+		prefix := "^(?:.*/)?"
+		if strings.Contains(p, "/") {
+			prefix = "^"
+			p = strings.TrimPrefix(p, "/")
+		}
+
+		re, err := regexp.Compile(prefix + globRegexp(p) + "$")
+		if err != nil {
+			return NewError("Error parsing ignore pattern " + p + ": " + err.Error())
+		}
+		rule.re = re
+		r.rules = append(r.rules, rule)
+	}
+
+	return nil
+}
+
+// globRegexp translates a gitignore glob into a regular expression.
+func globRegexp(glob string) string {
+	re := new(strings.Builder)
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return re.String()
+}
+
+// Match returns true if the rules ignore the named file or directory, without checking its parent directories.
+func (r *IgnoreRules) Match(name string, dir bool) bool {
+	if r == nil {
+		return false
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.dir && !dir {
+			continue
+		}
+		if rule.re.MatchString(name) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// Ignored returns true if the rules ignore the named file or any of its parent directories.
+func (r *IgnoreRules) Ignored(name string) bool {
+	if r == nil {
+		return false
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+
+	for i := 0; i < len(name); i++ {
+		if name[i] == '/' && r.Match(name[:i], true) {
+			return true
+		}
+	}
+
+	return r.Match(name, false)
+}
+
+// IsPrivate returns true if any element of the path starts with an underscore.
+// Private files of the public directory aren't built nor served, but can be used by other files,
+// i.e. as partials imported by stylesheets or scripts.
+func IsPrivate(name string) bool {
+	for _, elem := range strings.Split(path.Clean("/"+name), "/") {
+		if strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ignore sets the rules of the files to leave out of the public and templates file systems
+// when loading templates, building and serving.
+// Set them before loading templates for them to apply to the templates file systems.
+func (s *Service) Ignore(rules *IgnoreRules) {
+	s.Lock()
+	s.ignore = rules
+	s.Unlock()
+}
+
+// IsIgnored returns true if the named file of the public file system is private or ignored by the rules set with Ignore().
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) IsIgnored(name string) bool {
+	s.Lock()
+	rules := s.ignore
+	s.Unlock()
+
+	return IsPrivate(name) || rules.Ignored(name)
+}
@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules, err := ParseIgnore(`# Editor files
+.DS_Store
+*.sw?
+\#notes.txt
+
+/drafts/
+src/**/*.psd
+!src/keep.psd
+logs/**
+cache/
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		".DS_Store":             true,
+		"css/.DS_Store":         true,
+		"index.html.swp":        true,
+		"index.html":            false,
+		"#notes.txt":            true,
+		"drafts/post.html":      true,
+		"blog/drafts/post.html": false,
+		"src/logo.psd":          true,
+		"src/a/b/logo.psd":      true,
+		"src/keep.psd":          false,
+		"logo.psd":              false,
+		"logs/a/b.log":          true,
+		"cache":                 false,
+		"a/cache/file.txt":      true,
+	}
+	for name, expected := range tests {
+		if rules.Ignored(name) != expected {
+			t.Errorf("Expected Ignored(%s) to be %v", name, expected)
+		}
+	}
+
+	var empty *IgnoreRules
+	if empty.Ignored("index.html") {
+		t.Error("Expected nil rules to ignore nothing")
+	}
+
+	if !IsPrivate("css/_base.scss") || !IsPrivate("/_drafts/index.html") || IsPrivate("css/site.css") {
+		t.Error("Unexpected IsPrivate result")
+	}
+}
+
+func TestIgnoreBuild(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"index.html":          &fstest.MapFile{Data: []byte(`{{ template "layout.html" }}`)},
+		"index.html.swp":      &fstest.MapFile{Data: []byte(`{{ broken`)},
+		".git/HEAD":           &fstest.MapFile{Data: []byte("ref: refs/heads/master")},
+		"_drafts/post.html":   &fstest.MapFile{Data: []byte("Draft")},
+		"css/_vars.css":       &fstest.MapFile{Data: []byte(":root{}")},
+		"css/site.css":        &fstest.MapFile{Data: []byte("body{}")},
+		".well-known/app.txt": &fstest.MapFile{Data: []byte("ok")},
+	}
+	templatesFS := fstest.MapFS{
+		"layout.html":     &fstest.MapFile{Data: []byte(`Layout`)},
+		"layout.html.swp": &fstest.MapFile{Data: []byte(`{{ broken`)},
+		"old/layout.html": &fstest.MapFile{Data: []byte(`{{ broken`)},
+	}
+
+	rules, err := NewIgnoreRules("*.swp", ".git/", "old/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(".html", ".swp")
+	s.Ignore(rules)
+	err = s.LoadFS(templatesFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "css/site.css", ".well-known/app.txt"} {
+		if _, err := fs.Stat(out, name); err != nil {
+			t.Errorf("Expected %s in build output: %s", name, err)
+		}
+	}
+	for _, name := range []string{"index.html.swp", ".git", "_drafts", "css/_vars.css"} {
+		if _, err := fs.Stat(out, name); err == nil {
+			t.Errorf("Unexpected %s in build output", name)
+		}
+	}
+
+	if !s.IsIgnored("_drafts/post.html") || !s.IsIgnored(".git/HEAD") || s.IsIgnored("index.html") {
+		t.Error("Unexpected IsIgnored result")
+	}
+}
@@ -0,0 +1,456 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultImageQuality int = 85
+)
+
+// Image is returned by the "image" template function.
+// It holds the generated variants of a source image and the values needed to emit responsive <img> tags.
+type Image struct {
+	// Src is the URL of the largest variant, to be used as the fallback "src" attribute.
+	Src string
+
+	// Width and Height of the largest variant.
+	Width  int
+	Height int
+
+	// Srcset is the value for the "srcset" attribute.
+	Srcset string
+
+	// Sizes is the value for the "sizes" attribute, as provided by the "sizes=" parameter.
+	Sizes string
+
+	// Variants lists all generated images, sorted by width.
+	Variants []ImageVariant
+}
+
+// ImageVariant is a single resized version of a source image.
+type ImageVariant struct {
+	Src    string
+	Width  int
+	Height int
+}
+
+// imageParams holds the parsed arguments of the "image" template function.
+type imageParams struct {
+	widths  []int
+	ratio   float64
+	format  string
+	quality int
+	sizes   string
+}
+
+// Cache sets the directory used to store processed assets between builds.
+func (s *Service) Cache(dir string) {
+	s.cacheDir = dir
+}
+
+// BasePath sets the URL path the site is served under, like "/site", prepended to the URLs of the processed images
+// and component assets returned to templates. Assets are still written to the root of the build output.
+func (s *Service) BasePath(p string) {
+	p = strings.Trim(p, "/")
+	if p != "" {
+		p = "/" + p
+	}
+	s.basePath = p
+}
+
+// Public sets the web root directory used to resolve assets referenced from templates.
+// Build() sets it to its input directory.
+func (s *Service) Public(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return NewError("Error locating public directory " + dir + ": " + err.Error())
+	}
+	s.publicDir = abs
+	s.publicFS = os.DirFS(abs)
+
+	return nil
+}
+
+// PublicFS sets the web root file system used by RenderFile() and to resolve assets referenced from templates.
+// BuildFS() sets it to its input file system.
+func (s *Service) PublicFS(fsys fs.FS) {
+	s.publicDir = ""
+	s.publicFS = fsys
+}
+
+// Cached returns the location of a processed asset in the cache directory by its output name, if it exists.
+func (s *Service) Cached(name string) (string, bool) {
+	return CachedAsset(s.cacheDir, name)
+}
+
+// CachedAsset returns the location of a processed asset in the provided cache directory by its output name, if it exists.
+// An empty directory stands for the default one of a Service without Cache() set.
+func CachedAsset(dir, name string) (string, bool) {
+	fn := filepath.Join(cacheRoot(dir), filepath.FromSlash(path.Clean("/"+name)))
+	if info, err := os.Stat(fn); err == nil && !info.IsDir() {
+		return fn, true
+	}
+
+	return "", false
+}
+
+// cacheRoot returns the configured cache directory or a default one under the system temp dir.
+func (s *Service) cacheRoot() string {
+	return cacheRoot(s.cacheDir)
+}
+
+// cacheRoot returns the provided cache directory or a default one under the system temp dir when empty.
+func cacheRoot(dir string) string {
+	if dir != "" {
+		return dir
+	}
+
+	return filepath.Join(os.TempDir(), "thtml-cache")
+}
+
+// generate registers a processed asset to be written to the build output.
+func (s *Service) generate(name, cached string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.generated == nil {
+		s.generated = make(map[string]string)
+	}
+	s.generated[name] = cached
+}
+
+// Image resizes, crops and re-encodes the image at src (relative to the public directory) into multiple widths.
+// Parameters are "key=value" strings:
+//
+//  widths=320,640,1280      Output widths. Defaults to the source width. Images are never upscaled.
+//  crop=16:9                Crops the image to the given aspect ratio, centered, before resizing.
+//  format=jpeg|png|gif      Output format. Defaults to the source format.
+//  quality=85               JPEG quality.
+//  sizes=(min-width: 768px) 50vw, 100vw
+//                           Value passed through to Image.Sizes.
+//
+// Processed images are stored in the cache directory keyed by source content and parameters,
+// so they're only generated once between builds.
+func (s *Service) Image(src string, params ...string) (*Image, error) {
+	p, err := parseImageParams(params)
+	if err != nil {
+		return nil, NewError("Error processing image " + src + ": " + err.Error())
+	}
+
+	// Read source
+	if s.publicFS == nil {
+		return nil, NewError("Error reading image " + src + ": public file system not set")
+	}
+	name := path.Clean("/" + src)
+	content, err := fs.ReadFile(s.publicFS, name[1:])
+	if err != nil {
+		return nil, NewError("Error reading image " + src + ": " + err.Error())
+	}
+
+	// Cache key
+	sum := sha256.New()
+	sum.Write(content)
+	sum.Write([]byte(p.String()))
+	key := hex.EncodeToString(sum.Sum(nil))[:16]
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	meta := filepath.Join(s.cacheRoot(), filepath.FromSlash(base+"-"+key+".json"))
+
+	// Load from cache or process
+	var variants []ImageVariant
+	if buff, err := ioutil.ReadFile(meta); err == nil && json.Unmarshal(buff, &variants) == nil {
+		for _, v := range variants {
+			if _, ok := s.Cached(v.Src); !ok {
+				variants = nil
+				break
+			}
+		}
+	}
+	if variants == nil {
+		variants, err = s.processImage(content, base, ext, key, p)
+		if err != nil {
+			return nil, NewError("Error processing image " + src + ": " + err.Error())
+		}
+
+		buff, err := json.Marshal(variants)
+		if err != nil {
+			return nil, NewError("Error caching image " + src + ": " + err.Error())
+		}
+		err = ioutil.WriteFile(meta, buff, 0644)
+		if err != nil {
+			return nil, NewError("Error caching image " + src + ": " + err.Error())
+		}
+	}
+
+	// Register outputs and build result
+	img := &Image{
+		Sizes:    p.sizes,
+		Variants: make([]ImageVariant, len(variants)),
+	}
+	srcset := make([]string, 0, len(variants))
+	for i, v := range variants {
+		cached, _ := s.Cached(v.Src)
+		s.generate(v.Src, cached)
+
+		v.Src = s.basePath + v.Src
+		img.Variants[i] = v
+		srcset = append(srcset, v.Src+" "+strconv.Itoa(v.Width)+"w")
+	}
+	img.Srcset = strings.Join(srcset, ", ")
+	if len(variants) > 0 {
+		last := img.Variants[len(variants)-1]
+		img.Src, img.Width, img.Height = last.Src, last.Width, last.Height
+	}
+
+	return img, nil
+}
+
+// processImage decodes the source image and writes all requested variants into the cache directory.
+func (s *Service) processImage(content []byte, base, ext, key string, p imageParams) ([]ImageVariant, error) {
+	src, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if p.format != "" {
+		format = p.format
+	}
+	switch format {
+	case "jpeg":
+		if ext != ".jpg" && ext != ".jpeg" {
+			ext = ".jpg"
+		}
+	case "png", "gif":
+		ext = "." + format
+	default:
+		return nil, NewError("Unsupported image format " + format)
+	}
+
+	// Crop
+	if p.ratio > 0 {
+		src = cropImage(src, p.ratio)
+	}
+
+	widths := p.widths
+	if len(widths) == 0 {
+		widths = []int{src.Bounds().Dx()}
+	}
+
+	variants := make([]ImageVariant, 0, len(widths))
+	seen := make(map[int]bool)
+	for _, w := range widths {
+		// Never upscale
+		if w > src.Bounds().Dx() {
+			w = src.Bounds().Dx()
+		}
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+
+		h := src.Bounds().Dy() * w / src.Bounds().Dx()
+		if h < 1 {
+			h = 1
+		}
+		dst := resizeImage(src, w, h)
+
+		buff := new(bytes.Buffer)
+		switch format {
+		case "jpeg":
+			err = jpeg.Encode(buff, dst, &jpeg.Options{Quality: p.quality})
+		case "png":
+			err = png.Encode(buff, dst)
+		case "gif":
+			err = gif.Encode(buff, dst, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		v := ImageVariant{
+			Src:    base + "-" + strconv.Itoa(w) + "w-" + key[:8] + ext,
+			Width:  w,
+			Height: h,
+		}
+		fn := filepath.Join(s.cacheRoot(), filepath.FromSlash(v.Src))
+		err = os.MkdirAll(filepath.Dir(fn), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = ioutil.WriteFile(fn, buff.Bytes(), 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, v)
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].Width < variants[j].Width
+	})
+
+	return variants, nil
+}
+
+// cropImage returns the centered region of src matching the provided width/height ratio.
+func cropImage(src image.Image, ratio float64) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if float64(w)/float64(h) > ratio {
+		w = int(float64(h) * ratio)
+	} else {
+		h = int(float64(w) / ratio)
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	x := b.Min.X + (b.Dx()-w)/2
+	y := b.Min.Y + (b.Dy()-h)/2
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			dst.Set(i, j, src.At(x+i, y+j))
+		}
+	}
+
+	return dst
+}
+
+// resizeImage scales src to w x h pixels by averaging the source area covered by each output pixel.
+func resizeImage(src image.Image, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for j := 0; j < h; j++ {
+		y0 := b.Min.Y + j*b.Dy()/h
+		y1 := b.Min.Y + (j+1)*b.Dy()/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for i := 0; i < w; i++ {
+			x0 := b.Min.X + i*b.Dx()/w
+			x1 := b.Min.X + (i+1)*b.Dx()/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					c := color.NRGBA64Model.Convert(src.At(x, y)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+
+			dst.SetNRGBA(i, j, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
+
+// parseImageParams reads the "key=value" arguments of the "image" template function.
+func parseImageParams(params []string) (imageParams, error) {
+	p := imageParams{
+		quality: defaultImageQuality,
+	}
+
+	for _, param := range params {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return p, NewError("Invalid image parameter " + param)
+		}
+		k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		switch k {
+		case "widths":
+			for _, w := range strings.Split(v, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(w))
+				if err != nil || n < 1 {
+					return p, NewError("Invalid image width " + w)
+				}
+				p.widths = append(p.widths, n)
+			}
+
+		case "crop":
+			wh := strings.SplitN(v, ":", 2)
+			if len(wh) != 2 {
+				return p, NewError("Invalid image crop ratio " + v)
+			}
+			w, err := strconv.ParseFloat(wh[0], 64)
+			if err != nil || w <= 0 {
+				return p, NewError("Invalid image crop ratio " + v)
+			}
+			h, err := strconv.ParseFloat(wh[1], 64)
+			if err != nil || h <= 0 {
+				return p, NewError("Invalid image crop ratio " + v)
+			}
+			p.ratio = w / h
+
+		case "format":
+			p.format = strings.ToLower(v)
+			if p.format == "jpg" {
+				p.format = "jpeg"
+			}
+
+		case "quality":
+			q, err := strconv.Atoi(v)
+			if err != nil || q < 1 || q > 100 {
+				return p, NewError("Invalid image quality " + v)
+			}
+			p.quality = q
+
+		case "sizes":
+			p.sizes = v
+
+		default:
+			return p, NewError("Unknown image parameter " + k)
+		}
+	}
+
+	return p, nil
+}
+
+// String returns the parameters affecting the image output, used to build the cache key.
+func (p imageParams) String() string {
+	widths := make([]string, len(p.widths))
+	for i, w := range p.widths {
+		widths[i] = strconv.Itoa(w)
+	}
+
+	return "widths=" + strings.Join(widths, ",") +
+		";ratio=" + strconv.FormatFloat(p.ratio, 'f', -1, 64) +
+		";format=" + p.format +
+		";quality=" + strconv.Itoa(p.quality)
+}
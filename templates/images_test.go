@@ -0,0 +1,141 @@
+package templates
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestImage(t *testing.T, fn string, w, h int) {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	buff := new(bytes.Buffer)
+	err := png.Encode(buff, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Dir(fn), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(fn, buff.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestImage(t, filepath.Join(dir, "public", "img", "test.png"), 100, 50)
+
+	s := new(Service)
+	s.Cache(filepath.Join(dir, "cache"))
+	err = s.Public(filepath.Join(dir, "public"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := s.Image("img/test.png", "widths=20,40,200", "crop=1:1", "sizes=50vw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(img.Variants) != 3 {
+		t.Fatalf("Expected 3 variants. Got %d", len(img.Variants))
+	}
+	expected := []int{20, 40, 50}
+	for i, v := range img.Variants {
+		if v.Width != expected[i] || v.Height != expected[i] {
+			t.Errorf("Expected variant %dx%d. Got %dx%d", expected[i], expected[i], v.Width, v.Height)
+		}
+		if _, ok := s.Cached(v.Src); !ok {
+			t.Errorf("Expected variant %s to be cached", v.Src)
+		}
+	}
+	if img.Src != img.Variants[2].Src || img.Width != 50 || img.Height != 50 {
+		t.Errorf("Expected fallback to be the largest variant. Got %s %dx%d", img.Src, img.Width, img.Height)
+	}
+	if img.Sizes != "50vw" {
+		t.Errorf("Expected sizes '50vw'. Got '%s'", img.Sizes)
+	}
+
+	// Cached result
+	cached, err := s.Image("img/test.png", "widths=20,40,200", "crop=1:1", "sizes=50vw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Srcset != img.Srcset {
+		t.Errorf("Expected cached srcset '%s'. Got '%s'", img.Srcset, cached.Srcset)
+	}
+
+	// Base path
+	s.BasePath("/site/")
+	prefixed, err := s.Image("img/test.png", "widths=20,40,200", "crop=1:1", "sizes=50vw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefixed.Src != "/site"+img.Src || prefixed.Variants[0].Src != "/site"+img.Variants[0].Src {
+		t.Errorf("Expected sources under '/site'. Got '%s', '%s'", prefixed.Src, prefixed.Variants[0].Src)
+	}
+	if _, ok := s.generated[img.Src]; !ok {
+		t.Errorf("Expected %s to be generated at the output root", img.Src)
+	}
+
+	// Invalid parameters
+	_, err = s.Image("img/test.png", "widths=abc")
+	if err == nil {
+		t.Error("Expected error on invalid widths")
+	}
+}
+
+func TestBuildImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestImage(t, filepath.Join(dir, "public", "img", "test.png"), 64, 64)
+	err = ioutil.WriteFile(filepath.Join(dir, "public", "index.html"), []byte(`{{ $img := image "img/test.png" "widths=16,32" }}<img src="{{ $img.Src }}" srcset="{{ $img.Srcset }}">`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := new(Service)
+	s.AddExtension(".html")
+	s.Cache(filepath.Join(dir, "cache"))
+	err = s.Load("../_example/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Build(filepath.Join(dir, "public"), filepath.Join(dir, "build"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := s.Image("img/test.png", "widths=16,32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range img.Variants {
+		if _, err := os.Stat(filepath.Join(dir, "build", filepath.FromSlash(v.Src))); err != nil {
+			t.Errorf("Expected variant %s in build output: %s", v.Src, err)
+		}
+	}
+}
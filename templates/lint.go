@@ -0,0 +1,318 @@
+package templates
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Severity of lint issues.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a problem found by Lint() in a template or page.
+type LintIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// String returns the issue as "file:line: severity: message".
+func (i LintIssue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc += ":" + strconv.Itoa(i.Line)
+	}
+	return loc + ": " + i.Severity + ": " + i.Message
+}
+
+// sourceFile is a template or page parsed on its own, with the templates it defines and references.
+type sourceFile struct {
+	name string
+	root int
+	page bool
+
+	// Parse error, if any
+	err error
+
+	// Parse trees of the file and its define and block templates, by name
+	trees map[string]*parse.Tree
+
+	// Templates called from the file, and components and partials rendered with a constant name
+	refs []sourceRef
+}
+
+// sourceRef is a reference from a source file to a template.
+type sourceRef struct {
+	// Template of the file making the reference
+	from string
+
+	// Referenced template names, in lookup order
+	names []string
+
+	// Kind of reference: "template", "component" or "partial"
+	kind string
+	line int
+}
+
+// parseSources parses each template of the roots and each page of the public file system on its own,
+// without executing them. Files failing to parse are returned with their error.
+func (s *Service) parseSources(roots []fs.FS) ([]*sourceFile, error) {
+	var files []*sourceFile
+
+	parseRoot := func(fsys fs.FS, root int, page bool) error {
+		return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if name != "." && ((page && IsPrivate(name)) || s.ignore.Match(name, d.IsDir())) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !s.ValidExtension(path.Ext(name)) {
+				return nil
+			}
+
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			files = append(files, s.parseSource(name, root, page, content))
+			return nil
+		})
+	}
+
+	for i, root := range roots {
+		err := parseRoot(root, i, false)
+		if err != nil {
+			return nil, NewError("Error reading templates: " + err.Error())
+		}
+	}
+	if s.publicFS != nil {
+		err := parseRoot(s.publicFS, -1, true)
+		if err != nil {
+			return nil, NewError("Error reading pages: " + err.Error())
+		}
+	}
+
+	return files, nil
+}
+
+// parseSource parses the content of a template or page.
+func (s *Service) parseSource(name string, root int, page bool, content []byte) *sourceFile {
+	f := &sourceFile{name: name, root: root, page: page, trees: make(map[string]*parse.Tree)}
+
+	if page {
+		var err error
+		_, content, err = ParseFrontMatter(content)
+		if err != nil {
+			f.err = err
+			return f
+		}
+	}
+
+	tpl, err := template.New(name).Funcs(FuncMap).Funcs(s.funcs()).Parse(string(content))
+	if err != nil {
+		f.err = err
+		return f
+	}
+
+	for _, t := range tpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		f.trees[t.Name()] = t.Tree
+
+		tree := t.Tree
+		walkTree(tree.Root, func(node parse.Node) {
+			switch n := node.(type) {
+			case *parse.TemplateNode:
+				f.refs = append(f.refs, sourceRef{
+					from:  t.Name(),
+					names: []string{strings.TrimPrefix(n.Name, ParentPrefix)},
+					kind:  "template",
+					line:  nodeLine(tree, n),
+				})
+
+			case *parse.CommandNode:
+				if len(n.Args) < 2 {
+					return
+				}
+				id, ok := n.Args[0].(*parse.IdentifierNode)
+				if !ok || (id.Ident != "component" && id.Ident != "partial") {
+					return
+				}
+				str, ok := n.Args[1].(*parse.StringNode)
+				if !ok {
+					return
+				}
+				ref := sourceRef{from: t.Name(), names: []string{str.Text}, kind: id.Ident, line: nodeLine(tree, n)}
+				if id.Ident == "component" {
+					ref.names = append(ref.names, componentsDir+str.Text+".html")
+				}
+				f.refs = append(f.refs, ref)
+			}
+		})
+	}
+
+	return f
+}
+
+// nodeLine returns the line of the node in the file of the parse tree.
+func nodeLine(tree *parse.Tree, node parse.Node) int {
+	loc, _ := tree.ErrorContext(node)
+	parts := strings.Split(loc, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
+
+// Lint parses all templates in the provided roots, or in the loaded roots when none are provided,
+// and all pages of the public file system, without executing them. It returns the issues found sorted by file and line:
+//
+// Errors for files that fail to parse (i.e. unknown functions or unclosed actions),
+// calls to undefined templates and components, and templates defined in more than one file of the same root.
+//
+// Warnings for define blocks never called by a template or layout, and templates never referenced by others or by pages.
+//
+// Files are parsed on their own rather than taken from the loaded templates,
+// as templates defined in more than one file are merged into one once loaded, hiding the duplicates.
+func (s *Service) Lint(roots ...fs.FS) ([]LintIssue, error) {
+	if len(roots) == 0 {
+		s.Lock()
+		roots = s.tplRoots
+		s.Unlock()
+	}
+
+	files, err := s.parseSources(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LintIssue
+	add := func(file string, line int, severity, msg string) {
+		issues = append(issues, LintIssue{File: file, Line: line, Severity: severity, Message: msg})
+	}
+
+	// Parse errors and definitions
+	defined := make(map[string]bool)
+	definedIn := make(map[int]map[string]string)
+	for _, f := range files {
+		if f.err != nil {
+			add(f.name, parseErrorLine(f.err), LintError, parseErrorMessage(f.err))
+			continue
+		}
+		if f.page {
+			continue
+		}
+
+		defined[f.name] = true
+		if definedIn[f.root] == nil {
+			definedIn[f.root] = make(map[string]string)
+		}
+		for _, name := range sortedTrees(f) {
+			if name == f.name {
+				continue
+			}
+			defined[name] = true
+
+			// Empty definitions don't replace others
+			if parse.IsEmptyTree(f.trees[name].Root) {
+				continue
+			}
+			if other, ok := definedIn[f.root][name]; ok && other != f.name {
+				add(f.name, nodeLine(f.trees[name], f.trees[name].Root), LintError,
+					"template "+strconv.Quote(name)+" is also defined in "+other)
+				continue
+			}
+			definedIn[f.root][name] = f.name
+		}
+	}
+
+	// References
+	used := make(map[string]bool)
+	for _, f := range files {
+		if f.err != nil {
+			continue
+		}
+		for _, ref := range f.refs {
+			found := false
+			for _, name := range ref.names {
+				if defined[name] || (f.page && f.trees[name] != nil) {
+					used[name] = true
+					found = true
+					break
+				}
+			}
+			if !found {
+				add(f.name, ref.line, LintError, ref.kind+" "+strconv.Quote(ref.names[0])+" is not defined")
+			}
+		}
+	}
+
+	// Unused templates
+	for _, f := range files {
+		if f.err != nil {
+			continue
+		}
+		if !f.page && !used[f.name] && f.trees[f.name] != nil && !parse.IsEmptyTree(f.trees[f.name].Root) {
+			add(f.name, 0, LintWarning, "template is never referenced")
+		}
+		for _, name := range sortedTrees(f) {
+			if name == f.name || used[name] {
+				continue
+			}
+			add(f.name, nodeLine(f.trees[name], f.trees[name].Root), LintWarning,
+				"define "+strconv.Quote(name)+" is never used")
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+
+	return issues, nil
+}
+
+// sortedTrees returns the names of the templates defined by the file, sorted.
+func sortedTrees(f *sourceFile) []string {
+	names := make([]string, 0, len(f.trees))
+	for name := range f.trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseErrorLine returns the line of a parse error, or 0 when unknown.
+func parseErrorLine(err error) int {
+	m := errorLocation.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[2])
+	return line
+}
+
+// parseErrorMessage returns the message of a parse error without its location.
+func parseErrorMessage(err error) string {
+	msg := err.Error()
+	if loc := errorLocation.FindStringIndex(msg); loc != nil {
+		msg = msg[loc[1]:]
+	}
+	return strings.TrimSpace(msg)
+}
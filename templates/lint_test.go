@@ -0,0 +1,53 @@
+package templates
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLint(t *testing.T) {
+	tplFS := fstest.MapFS{
+		"layouts/default.html": &fstest.MapFile{Data: []byte("<title>{{ block \"title\" . }}{{ end }}</title>\n{{ template \"footer\" . }}\n{{ template \"missing.html\" }}")},
+		"layouts/unused.html":  &fstest.MapFile{Data: []byte(`Unused`)},
+		"components/nav.html":  &fstest.MapFile{Data: []byte(`<nav></nav>`)},
+		"partials.html":        &fstest.MapFile{Data: []byte("{{ define \"footer\" }}Footer{{ end }}\n{{ define \"header\" }}Header{{ end }}")},
+		"dup.html":             &fstest.MapFile{Data: []byte(`{{ define "footer" }}Other{{ end }}{{ template "dup-ref" }}`)},
+		"broken.html":          &fstest.MapFile{Data: []byte("ok\n{{ unknownFunc }}")},
+		"unclosed.html":        &fstest.MapFile{Data: []byte("ok\n\n{{ .Title ")},
+	}
+	publicFS := fstest.MapFS{
+		"index.html":    &fstest.MapFile{Data: []byte("---\ntitle: Home\n---\n{{ template \"layouts/default.html\" }}{{ define \"title\" }}Home{{ end }}{{ component \"nav\" }}")},
+		"about.html":    &fstest.MapFile{Data: []byte(`{{ define "sidebar" }}Side{{ end }}{{ partial "dup.html" }}`)},
+		"_private.html": &fstest.MapFile{Data: []byte(`{{ template "nope" }}`)},
+	}
+
+	s := New(".html")
+	s.PublicFS(publicFS)
+	issues, err := s.Lint(tplFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`about.html:1: warning: define "sidebar" is never used`,
+		`broken.html:2: error: function "unknownFunc" not defined`,
+		`dup.html:1: error: template "dup-ref" is not defined`,
+		`layouts/default.html:3: error: template "missing.html" is not defined`,
+		`layouts/unused.html: warning: template is never referenced`,
+		`partials.html:1: error: template "footer" is also defined in dup.html`,
+		`partials.html:2: warning: define "header" is never used`,
+		`unclosed.html:3: error: unclosed action`,
+	}
+	got := make([]string, len(issues))
+	for i, issue := range issues {
+		got[i] = issue.String()
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d issues. Got %d:\n%v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected '%s'. Got '%s'", expected[i], got[i])
+		}
+	}
+}
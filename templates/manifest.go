@@ -0,0 +1,221 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ManifestFile is the name of the build manifest written to the build output.
+const ManifestFile = "build-manifest.json"
+
+// Manifest describes the output of a build.
+type Manifest struct {
+	Files    []ManifestEntry `json:"files"`
+	Warnings []string       `json:"warnings"`
+}
+
+// ManifestEntry is an output file of a build.
+type ManifestEntry struct {
+	// Output path
+	Path string `json:"path"`
+
+	// Public files the output was built from, if any
+	Sources []string `json:"sources,omitempty"`
+
+	// Media type
+	Type string `json:"type"`
+
+	// Size in bytes, and before minification
+	Size    int `json:"size"`
+	RawSize int `json:"rawSize"`
+
+	// Hex encoded SHA-256 of the content
+	Hash string `json:"hash"`
+
+	// Time spent building the output, in milliseconds
+	Duration float64 `json:"durationMs"`
+}
+
+// ContentType returns the media type of a file by its name extension, or detected from its content.
+func ContentType(name string, content []byte) string {
+	switch path.Ext(name) {
+	case ".html":
+		return "text/html; charset=utf-8"
+
+	case ".js":
+		return "application/javascript; charset=utf-8"
+
+	case ".css":
+		return "text/css; charset=utf-8"
+
+	case ".svg":
+		return "image/svg+xml; charset=utf-8"
+
+	case ".map", ".json":
+		return "application/json; charset=utf-8"
+
+	default:
+		return http.DetectContentType(content)
+	}
+}
+
+// Manifest returns the manifest of the last build.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Manifest() *Manifest {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.manifest
+}
+
+// buildReport collects the output files of the build in progress.
+type buildReport struct {
+	sync.Mutex
+
+	files    map[string]*ManifestEntry
+	raw      map[string]int
+	warnings []string
+
+	// Build step in progress
+	sources []string
+	start   time.Time
+}
+
+func newBuildReport() *buildReport {
+	return &buildReport{
+		files: make(map[string]*ManifestEntry),
+		raw:   make(map[string]int),
+	}
+}
+
+// begin starts a build step, attributing the files written until the next step to the sources.
+func (r *buildReport) begin(sources ...string) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	r.sources = sources
+	r.start = time.Now()
+	r.Unlock()
+}
+
+// rawSize records the size of an output before minification.
+func (r *buildReport) rawSize(name string, size int) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	r.raw[strings.TrimPrefix(name, "/")] = size
+	r.Unlock()
+}
+
+// warn adds a warning to the build report.
+func (r *buildReport) warn(msg string) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	r.warnings = append(r.warnings, msg)
+	r.Unlock()
+}
+
+// write records an output file written by the current build step.
+func (r *buildReport) write(name string, data []byte) {
+	name = path.Clean("/" + name)[1:]
+	sum := sha256.Sum256(data)
+
+	r.Lock()
+	defer r.Unlock()
+
+	// Precompressed siblings follow their file
+	if prev, ok := r.files[name]; ok && !isPrecompressed(name) {
+		r.warnings = append(r.warnings, "Output "+name+" from "+strings.Join(r.sources, ", ")+
+			" replaces the one from "+strings.Join(prev.Sources, ", "))
+	}
+
+	raw, ok := r.raw[name]
+	if !ok {
+		raw = len(data)
+	}
+	r.files[name] = &ManifestEntry{
+		Path:     name,
+		Sources:  r.sources,
+		Type:     ContentType(name, data),
+		Size:     len(data),
+		RawSize:  raw,
+		Hash:     hex.EncodeToString(sum[:]),
+		Duration: float64(time.Since(r.start).Microseconds()) / 1000,
+	}
+}
+
+// isPrecompressed returns true for the names of precompressed siblings.
+func isPrecompressed(name string) bool {
+	for _, ext := range encodingExts {
+		if path.Ext(name) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// manifest returns the collected files sorted by path.
+func (r *buildReport) manifest() *Manifest {
+	r.Lock()
+	defer r.Unlock()
+
+	m := &Manifest{
+		Files:    make([]ManifestEntry, 0, len(r.files)),
+		Warnings: append([]string{}, r.warnings...),
+	}
+	for _, f := range r.files {
+		m.Files = append(m.Files, *f)
+	}
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+
+	return m
+}
+
+// reportFS records the files written to the build output in the build report.
+type reportFS struct {
+	WriteFS
+	report *buildReport
+}
+
+// WriteFile implements WriteFS
+func (r *reportFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	err := r.WriteFS.WriteFile(name, data, perm)
+	if err != nil {
+		return err
+	}
+
+	r.report.write(name, data)
+	return nil
+}
+
+// writeManifest writes the manifest of the build to the build output.
+func (s *Service) writeManifest(out WriteFS, m *Manifest) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return NewError("Error encoding build manifest: " + err.Error())
+	}
+
+	err = out.WriteFile(ManifestFile, append(content, '\n'), 0644)
+	if err != nil {
+		return NewError("Error writing build manifest: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifest(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"index.html":     &fstest.MapFile{Data: []byte("<p>\n  {{ template \"t.html\" }}\n</p>")},
+		"empty.html":     &fstest.MapFile{Data: []byte("{{ if false }}x{{ end }}")},
+		"draft.html":     &fstest.MapFile{Data: []byte("---\ndraft: true\n---\nDraft")},
+		"img/logo.png":   &fstest.MapFile{Data: []byte("\x89PNG\r\n\x1a\n")},
+		"css/a.css":      &fstest.MapFile{Data: []byte("a {\n  color: red;\n}\n")},
+		"css/b.css":      &fstest.MapFile{Data: []byte("b {\n  color: blue;\n}\n")},
+		"css/theme.css":  &fstest.MapFile{Data: []byte("c {}")},
+		"css/theme.scss": &fstest.MapFile{Data: []byte("d { color: red; }")},
+	}
+
+	s, err := LoadFS(fstest.MapFS{
+		"t.html": &fstest.MapFile{Data: []byte("Hello")},
+	}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Minify(true)
+	s.Bundles(map[string][]string{"css/site.css": {"css/a.css", "css/b.css"}})
+
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Written manifest
+	content, err := fs.ReadFile(out, ManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Manifest)
+	err = json.Unmarshal(content, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]ManifestEntry)
+	var paths []string
+	for _, f := range m.Files {
+		files[f.Path] = f
+		paths = append(paths, f.Path)
+	}
+	expected := "css/a.css,css/b.css,css/site.css,css/site.css.map,css/theme.css,empty.html,img/logo.png,index.html"
+	if strings.Join(paths, ",") != expected {
+		t.Errorf("Expected manifest files %s. Got %s", expected, strings.Join(paths, ","))
+	}
+
+	index := files["index.html"]
+	sum := sha256.Sum256([]byte("<p>Hello</p>"))
+	if index.Type != "text/html; charset=utf-8" || index.Size != 12 || index.RawSize != 16 ||
+		index.Hash != hex.EncodeToString(sum[:]) ||
+		strings.Join(index.Sources, ",") != "index.html" || index.Duration <= 0 {
+		t.Errorf("Unexpected manifest entry %+v", index)
+	}
+	if files["img/logo.png"].Type != "image/png" {
+		t.Errorf("Expected image/png media type. Got %s", files["img/logo.png"].Type)
+	}
+	if site := files["css/site.css"]; strings.Join(site.Sources, ",") != "css/a.css,css/b.css" || site.RawSize <= site.Size {
+		t.Errorf("Unexpected bundle manifest entry %+v", site)
+	}
+
+	warnings := strings.Join(m.Warnings, "\n")
+	for _, w := range []string{
+		"Stylesheet css/theme.scss skipped: css/theme.css is in the public files",
+		"Skipped draft page draft.html",
+		"Page empty.html rendered empty",
+	} {
+		if !strings.Contains(warnings, w) {
+			t.Errorf("Expected warning '%s'. Got '%s'", w, warnings)
+		}
+	}
+
+	// Same manifest returned by the service
+	if got := s.Manifest(); got == nil || len(got.Files) != len(m.Files) {
+		t.Errorf("Expected the build manifest. Got %+v", got)
+	}
+}
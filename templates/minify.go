@@ -0,0 +1,196 @@
+package templates
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/json"
+	"github.com/tdewolff/minify/v2/svg"
+	"github.com/tdewolff/minify/v2/xml"
+)
+
+// MinifyOptions configures the minifier of each supported media type.
+type MinifyOptions struct {
+	HTML HTMLMinifyOptions `json:"html"`
+	CSS  CSSMinifyOptions  `json:"css"`
+	JS   JSMinifyOptions   `json:"js"`
+	SVG  SVGMinifyOptions  `json:"svg"`
+	JSON JSONMinifyOptions `json:"json"`
+	XML  XMLMinifyOptions  `json:"xml"`
+
+	// Exclude lists glob patterns (i.e. "*.min.js", "js/vendor/*") of files that won't be minified.
+	// Patterns without a slash are matched against the filename, otherwise against the path relative to the public directory.
+	Exclude []string `json:"exclude"`
+}
+
+// HTMLMinifyOptions configures the text/html minifier.
+type HTMLMinifyOptions struct {
+	Disabled                bool `json:"disabled"`
+	KeepComments            bool `json:"keepComments"`
+	KeepConditionalComments bool `json:"keepConditionalComments"`
+	KeepDefaultAttrVals     bool `json:"keepDefaultAttrVals"`
+	KeepDocumentTags        bool `json:"keepDocumentTags"`
+	KeepEndTags             bool `json:"keepEndTags"`
+	KeepQuotes              bool `json:"keepQuotes"`
+	KeepWhitespace          bool `json:"keepWhitespace"`
+}
+
+// CSSMinifyOptions configures the text/css minifier.
+type CSSMinifyOptions struct {
+	Disabled  bool `json:"disabled"`
+	KeepCSS2  bool `json:"keepCSS2"`
+	Precision int  `json:"precision"`
+}
+
+// JSMinifyOptions configures the application/javascript minifier.
+type JSMinifyOptions struct {
+	Disabled     bool `json:"disabled"`
+	KeepVarNames bool `json:"keepVarNames"`
+	Precision    int  `json:"precision"`
+}
+
+// SVGMinifyOptions configures the image/svg+xml minifier.
+type SVGMinifyOptions struct {
+	Disabled     bool `json:"disabled"`
+	KeepComments bool `json:"keepComments"`
+	Precision    int  `json:"precision"`
+}
+
+// JSONMinifyOptions configures the application/json minifier.
+type JSONMinifyOptions struct {
+	Disabled    bool `json:"disabled"`
+	KeepNumbers bool `json:"keepNumbers"`
+	Precision   int  `json:"precision"`
+}
+
+// XMLMinifyOptions configures the text/xml minifier.
+type XMLMinifyOptions struct {
+	Disabled       bool `json:"disabled"`
+	KeepWhitespace bool `json:"keepWhitespace"`
+}
+
+// mediaTypes maps the filename extensions of minifiable files to their media type.
+var mediaTypes = map[string]string{
+	".html": "text/html",
+	".css":  "text/css",
+	".js":   "application/javascript",
+	".svg":  "image/svg+xml",
+	".json": "application/json",
+	".xml":  "text/xml",
+}
+
+// DefaultMinifyOptions returns the options used when none are configured.
+func DefaultMinifyOptions() MinifyOptions {
+	return MinifyOptions{
+		HTML: HTMLMinifyOptions{
+			KeepConditionalComments: true,
+			KeepDocumentTags:        true,
+			KeepEndTags:             true,
+		},
+	}
+}
+
+// MinifyOptions sets the configuration of the minifiers used when minifying the output.
+func (s *Service) MinifyOptions(o MinifyOptions) {
+	m := minify.New()
+	jsTypes := regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$")
+
+	if !o.HTML.Disabled {
+		m.Add("text/html", &html.Minifier{
+			KeepComments:            o.HTML.KeepComments,
+			KeepConditionalComments: o.HTML.KeepConditionalComments,
+			KeepDefaultAttrVals:     o.HTML.KeepDefaultAttrVals,
+			KeepDocumentTags:        o.HTML.KeepDocumentTags,
+			KeepEndTags:             o.HTML.KeepEndTags,
+			KeepQuotes:              o.HTML.KeepQuotes,
+			KeepWhitespace:          o.HTML.KeepWhitespace,
+		})
+	}
+	if !o.CSS.Disabled {
+		m.Add("text/css", &css.Minifier{
+			KeepCSS2:  o.CSS.KeepCSS2,
+			Precision: o.CSS.Precision,
+		})
+	}
+	if !o.JS.Disabled {
+		m.AddRegexp(jsTypes, &js.Minifier{
+			KeepVarNames: o.JS.KeepVarNames,
+			Precision:    o.JS.Precision,
+		})
+	}
+	if !o.SVG.Disabled {
+		m.Add("image/svg+xml", &svg.Minifier{
+			KeepComments: o.SVG.KeepComments,
+			Precision:    o.SVG.Precision,
+		})
+	}
+	if !o.JSON.Disabled {
+		m.Add("application/json", &json.Minifier{
+			KeepNumbers: o.JSON.KeepNumbers,
+			Precision:   o.JSON.Precision,
+		})
+	}
+	if !o.XML.Disabled {
+		m.Add("text/xml", &xml.Minifier{
+			KeepWhitespace: o.XML.KeepWhitespace,
+		})
+	}
+
+	s.Lock()
+	s.minifier = m
+	s.minifyExclude = o.Exclude
+	s.Unlock()
+}
+
+// minifyType returns the media type to minify the named file with, or an empty string if it shouldn't be minified.
+// Names of files in the public directory start with a slash.
+func (s *Service) minifyType(name string) string {
+	if !s.minify {
+		return ""
+	}
+
+	mime, ok := mediaTypes[strings.ToLower(path.Ext(name))]
+	if !ok {
+		return ""
+	}
+
+	// Exclusions
+	s.Lock()
+	exclude := s.minifyExclude
+	s.Unlock()
+
+	rel := strings.TrimPrefix(name, "/")
+	for _, pattern := range exclude {
+		n := rel
+		if !strings.Contains(pattern, "/") {
+			n = path.Base(rel)
+		}
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), n); ok {
+			return ""
+		}
+	}
+
+	return mime
+}
+
+// getMinifier returns the configured minifier, creating the default one on first use.
+func (s *Service) getMinifier() *minify.M {
+	s.Lock()
+	m := s.minifier
+	s.Unlock()
+
+	if m == nil {
+		s.MinifyOptions(DefaultMinifyOptions())
+
+		s.Lock()
+		m = s.minifier
+		s.Unlock()
+	}
+
+	return m
+}
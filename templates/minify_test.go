@@ -0,0 +1,80 @@
+package templates
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMinifyOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"index.html":       "<html>\n  <body>\n    <!-- comment -->\n    <p>Hello</p>\n  </body>\n</html>\n",
+		"js/app.js":        "var  answer = 42 ;\n",
+		"js/vendor.min.js": "var  answer = 42 ;\n",
+		"data.json":        "{\n  \"answer\": 42\n}\n",
+		"icon.svg":         "<svg xmlns=\"http://www.w3.org/2000/svg\">\n  <rect width=\"10\" height=\"10\" />\n</svg>\n",
+	}
+	for name, content := range files {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(fn), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fn, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := new(Service)
+	s.AddExtension(".html")
+	s.Minify(true)
+	err = s.Load("../_example/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Public(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := DefaultMinifyOptions()
+	o.HTML.KeepComments = true
+	o.JSON.Disabled = true
+	o.Exclude = []string{"*.min.js"}
+	s.MinifyOptions(o)
+
+	render := func(name string) string {
+		buff := new(bytes.Buffer)
+		err := s.Render(buff, filepath.Join(dir, filepath.FromSlash(name)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buff.String()
+	}
+
+	if out := render("index.html"); !strings.Contains(out, "<!-- comment -->") || strings.Contains(out, "\n") {
+		t.Errorf("Expected minified HTML keeping comments. Got %q", out)
+	}
+	if out := render("js/app.js"); out != "var answer=42" {
+		t.Errorf("Expected minified JS. Got %q", out)
+	}
+	if out := render("js/vendor.min.js"); out != files["js/vendor.min.js"] {
+		t.Errorf("Expected excluded JS not to be minified. Got %q", out)
+	}
+	if out := render("data.json"); out != files["data.json"] {
+		t.Errorf("Expected disabled JSON minifier not to change output. Got %q", out)
+	}
+	if out := render("icon.svg"); strings.Contains(out, "\n") {
+		t.Errorf("Expected minified SVG. Got %q", out)
+	}
+}
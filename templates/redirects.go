@@ -0,0 +1,427 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedirectsFile is the public file listing redirect rules, one "from to [status]" rule per line.
+// Being private, it's never served nor copied to the build output.
+const RedirectsFile = "_redirects"
+
+// Formats of the redirect rule files written to the build output by RedirectFiles(), and their file names.
+var redirectFiles = map[string]string{
+	"netlify": "_redirects",
+	"nginx":   "redirects.nginx.conf",
+	"apache":  ".htaccess",
+}
+
+// Redirect is a rule redirecting requests for a path to another path or URL.
+type Redirect struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// HTTP status code: 301 (default), 302, 307 or 308
+	Status int `json:"status,omitempty"`
+}
+
+// ParseRedirects parses redirect rules in the format of RedirectsFile.
+// Empty lines and lines starting with "#" are skipped.
+func ParseRedirects(content []byte) ([]Redirect, error) {
+	var rules []Redirect
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, NewError("Error parsing redirect on line " + strconv.Itoa(i+1) + ": expected 'from to [status]'")
+		}
+		r := Redirect{From: fields[0], To: fields[1]}
+		if len(fields) == 3 {
+			status, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, NewError("Error parsing redirect on line " + strconv.Itoa(i+1) + ": invalid status " + fields[2])
+			}
+			r.Status = status
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// validate checks the rule and sets its default status.
+// Paths and targets can't contain whitespace, quotes, ";", "{" nor "}", to be written safely to the server rule files.
+func (r *Redirect) validate() error {
+	if !strings.HasPrefix(r.From, "/") {
+		return NewError("Error configuring redirect from " + r.From + ": the path must start with /")
+	}
+	if r.To == "" {
+		return NewError("Error configuring redirect from " + r.From + ": missing target")
+	}
+	if strings.ContainsAny(r.From+r.To, " \t\r\n\f\v;{}\"'") {
+		return NewError("Error configuring redirect from " + r.From + ": invalid character in path or target")
+	}
+
+	switch r.Status {
+	case 0:
+		r.Status = http.StatusMovedPermanently
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return NewError("Error configuring redirect from " + r.From + ": unsupported status " + strconv.Itoa(r.Status))
+	}
+
+	return nil
+}
+
+// redirectKey normalizes a request path, so "/about", "/about/", "/about.html" and "/about/index.html" match the same rule.
+func redirectKey(p string) string {
+	p = path.Clean("/" + p)
+	p = strings.TrimSuffix(p, "/index.html")
+	p = strings.TrimSuffix(p, ".html")
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// Redirects sets the configured redirect rules.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Redirects(rules []Redirect) error {
+	r := make([]Redirect, len(rules))
+	for i, rule := range rules {
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+		r[i] = rule
+	}
+
+	s.Lock()
+	s.redirects = r
+	s.Unlock()
+
+	return nil
+}
+
+// RedirectFiles sets the formats of the redirect rule files written to the build output:
+// "netlify" (_redirects), "nginx" (redirects.nginx.conf, to include in a server block) and "apache" (.htaccess).
+// The nginx and apache files also set the status pages of the error responses, see StatusPage.
+func (s *Service) RedirectFiles(formats ...string) error {
+	for _, f := range formats {
+		if _, ok := redirectFiles[f]; !ok {
+			return NewError("Error configuring redirect files: unsupported format " + f)
+		}
+	}
+
+	s.redirectFormats = formats
+	return nil
+}
+
+// publicRedirects are the redirect rules of a public file system, with the errors found reading them.
+type publicRedirects struct {
+	fingerprint string
+	rules       []Redirect
+
+	// Error reading the RedirectsFile, and error in the aliases of a page
+	fileErr  error
+	aliasErr error
+}
+
+// RedirectRules returns all redirect rules: the configured ones, the ones in the public RedirectsFile,
+// and the ones generated from the "aliases" of the published pages of the public file system.
+// Aliases are a comma separated list of paths in the front matter of a page redirecting to it:
+//
+//  ---
+//  aliases: /about-us.html, /company/
+//  ---
+//
+// Pages failing to parse are skipped, as rendering them reports the error.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) RedirectRules() ([]Redirect, error) {
+	s.Lock()
+	rules := append([]Redirect(nil), s.redirects...)
+	s.Unlock()
+
+	public, err := s.readPublicRedirects()
+	if err != nil {
+		return nil, err
+	}
+	if public.fileErr != nil {
+		return nil, public.fileErr
+	}
+	if public.aliasErr != nil {
+		return nil, public.aliasErr
+	}
+
+	return append(rules, public.rules...), nil
+}
+
+// MatchRedirect returns the first redirect rule matching the request path.
+// Invalid aliases of a page don't keep the other rules from matching.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) MatchRedirect(p string) (Redirect, bool, error) {
+	s.Lock()
+	rules := append([]Redirect(nil), s.redirects...)
+	s.Unlock()
+
+	public, err := s.readPublicRedirects()
+	if err != nil {
+		return Redirect{}, false, err
+	}
+	if public.fileErr != nil {
+		return Redirect{}, false, public.fileErr
+	}
+	rules = append(rules, public.rules...)
+
+	key := redirectKey(p)
+	for _, r := range rules {
+		if redirectKey(r.From) == key {
+			return r, true, nil
+		}
+	}
+
+	return Redirect{}, false, nil
+}
+
+// readPublicRedirects returns the redirect rules of the public file system,
+// reading them again only when their fingerprint changes.
+func (s *Service) readPublicRedirects() (*publicRedirects, error) {
+	if s.publicFS == nil {
+		return &publicRedirects{}, nil
+	}
+
+	fp, err := s.redirectsFingerprint()
+	if err != nil {
+		return nil, NewError("Error reading redirects: " + err.Error())
+	}
+	s.Lock()
+	cached := s.publicRedirects
+	s.Unlock()
+	if cached != nil && cached.fingerprint == fp {
+		return cached, nil
+	}
+
+	public := &publicRedirects{fingerprint: fp}
+
+	// Redirects file
+	content, err := fs.ReadFile(s.publicFS, RedirectsFile)
+	if err == nil {
+		public.fileErr = s.readRedirectsFile(public, content)
+	}
+
+	// Page aliases
+	now := time.Now()
+	err = fs.WalkDir(s.publicFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && (IsPrivate(name) || s.ignore.Match(name, d.IsDir())) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !s.ValidExtension(path.Ext(name)) {
+			return nil
+		}
+
+		content, err := fs.ReadFile(s.publicFS, name)
+		if err != nil || !bytes.HasPrefix(content, []byte(frontMatterDelim)) {
+			return err
+		}
+		matter, _, err := ParseFrontMatter(content)
+		if err != nil || matter["aliases"] == "" {
+			return nil
+		}
+		status, err := matter.Status(now)
+		if err != nil || !s.included(status) {
+			return nil
+		}
+
+		to := "/" + strings.TrimSuffix(name, "index.html")
+		for _, alias := range strings.Split(matter["aliases"], ",") {
+			r := Redirect{From: strings.TrimSpace(alias), To: to}
+			if r.From == "" {
+				continue
+			}
+			err = r.validate()
+			if err != nil {
+				if public.aliasErr == nil {
+					public.aliasErr = NewError("Error parsing aliases of " + name + ": " + err.Error())
+				}
+				continue
+			}
+			public.rules = append(public.rules, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, NewError("Error reading redirects: " + err.Error())
+	}
+
+	s.Lock()
+	s.publicRedirects = public
+	s.Unlock()
+
+	return public, nil
+}
+
+// redirectsFingerprint returns a hash of the sources of the redirect rules of the public file system:
+// the names, sizes and modification times of the RedirectsFile and of the files that aren't private nor ignored,
+// and the publishing status of the pages to build. It changes when any of them changes.
+func (s *Service) redirectsFingerprint() (string, error) {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%t %t %t\n", s.drafts, s.future, s.expired)
+	if info, err := fs.Stat(s.publicFS, RedirectsFile); err == nil {
+		fmt.Fprintf(sum, "%s %d %d\n", RedirectsFile, info.Size(), info.ModTime().UnixNano())
+	}
+
+	err := fs.WalkDir(s.publicFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && (IsPrivate(name) || s.ignore.Match(name, d.IsDir())) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(sum, "%s %d %d %s\n", name, info.Size(), info.ModTime().UnixNano(), info.Mode())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// readRedirectsFile adds the rules of the RedirectsFile content.
+func (s *Service) readRedirectsFile(public *publicRedirects, content []byte) error {
+	fileRules, err := ParseRedirects(content)
+	if err != nil {
+		return NewError("Error reading " + RedirectsFile + ": " + err.Error())
+	}
+	for _, r := range fileRules {
+		err = r.validate()
+		if err != nil {
+			return NewError("Error reading " + RedirectsFile + ": " + err.Error())
+		}
+		public.rules = append(public.rules, r)
+	}
+
+	return nil
+}
+
+// RedirectPage returns an HTML page redirecting browsers to the target with a meta refresh,
+// with a canonical link for search engines.
+func RedirectPage(to string) []byte {
+	u := html.EscapeString(to)
+	return []byte(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Redirecting to ` + u + `</title>
+<link rel="canonical" href="` + u + `">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url=` + u + `">
+</head>
+<body>
+<a href="` + u + `">Redirecting to ` + u + `</a>
+</body>
+</html>
+`)
+}
+
+// redirectStub returns the output file of the redirect page for the path:
+// the path itself for ".html" files, or its "index.html" otherwise.
+func redirectStub(p string) string {
+	p = path.Clean("/" + p)[1:]
+	if path.Ext(p) == ".html" {
+		return p
+	}
+	return path.Join(p, "index.html")
+}
+
+// buildRedirects writes the redirect pages and the configured redirect rule files to the build output.
+// Redirect pages never replace the output of a public file.
+func (s *Service) buildRedirects() error {
+	rules, err := s.RedirectRules()
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		stub := redirectStub(r.From)
+		if _, err := fs.Stat(s.buildFS, stub); err == nil {
+			s.report.warn("Redirect from " + r.From + " skipped: " + stub + " is already in the output")
+			continue
+		}
+
+		s.report.begin()
+		err = writeFile(s.buildFS, stub, RedirectPage(r.To))
+		if err != nil {
+			return NewError("Error writing redirect from " + r.From + ": " + err.Error())
+		}
+	}
+
+	// Rule files, with the status pages of the servers not finding them by name
+	pages := s.statusPages()
+	for _, format := range s.redirectFormats {
+		buff := new(bytes.Buffer)
+		for _, code := range sortedStatusCodes(pages) {
+			switch format {
+			case "nginx":
+				buff.WriteString("error_page " + strconv.Itoa(code) + " /" + pages[code] + ";\n")
+			case "apache":
+				buff.WriteString("ErrorDocument " + strconv.Itoa(code) + " /" + pages[code] + "\n")
+			}
+		}
+		for _, r := range rules {
+			switch format {
+			case "netlify":
+				buff.WriteString(r.From + " " + r.To + " " + strconv.Itoa(r.Status) + "\n")
+			case "nginx":
+				buff.WriteString("location = " + r.From + " { return " + strconv.Itoa(r.Status) + " " + r.To + "; }\n")
+			case "apache":
+				buff.WriteString("RedirectMatch " + strconv.Itoa(r.Status) + " " + apacheMatch(r.From) + " " + r.To + "\n")
+			}
+		}
+
+		s.report.begin()
+		err = s.buildFS.WriteFile(redirectFiles[format], buff.Bytes(), 0644)
+		if err != nil {
+			return NewError("Error writing " + format + " redirects: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// apacheMatch returns the regular expression matching only the path, with or without a trailing slash,
+// as Apache's Redirect matches any path starting with it.
+func apacheMatch(p string) string {
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return "^" + regexp.QuoteMeta(p) + "/?$"
+}
@@ -0,0 +1,160 @@
+package templates
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseRedirects(t *testing.T) {
+	rules, err := ParseRedirects([]byte("# Moved\n/old.html /new.html\n\n/blog/  https://blog.example.com/  302\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0] != (Redirect{From: "/old.html", To: "/new.html"}) ||
+		rules[1] != (Redirect{From: "/blog/", To: "https://blog.example.com/", Status: 302}) {
+		t.Errorf("Unexpected rules %v", rules)
+	}
+
+	for _, content := range []string{"/old.html", "/old.html /new.html 301 extra", "/old.html /new.html moved"} {
+		if _, err := ParseRedirects([]byte(content)); err == nil {
+			t.Errorf("Expected error parsing '%s'", content)
+		}
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"_redirects":       &fstest.MapFile{Data: []byte("/products/old.html /products/new.html 302\n")},
+		"about/index.html": &fstest.MapFile{Data: []byte("---\naliases: /about-us.html, /company/\n---\nAbout")},
+		"draft.html":       &fstest.MapFile{Data: []byte("---\ndraft: true\naliases: /draft-old.html\n---\nDraft")},
+		"kept.html":        &fstest.MapFile{Data: []byte("Kept")},
+	}
+
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PublicFS(publicFS)
+
+	if err := s.Redirects([]Redirect{{From: "old", To: "/new"}}); err == nil {
+		t.Error("Expected error for relative redirect path")
+	}
+	if err := s.Redirects([]Redirect{{From: "/old", To: "/new", Status: 200}}); err == nil {
+		t.Error("Expected error for unsupported status")
+	}
+	for _, r := range []Redirect{{From: "/a b", To: "/new"}, {From: "/old", To: "/new; return 200"}, {From: "/old", To: "/new\""}} {
+		if err := s.Redirects([]Redirect{r}); err == nil {
+			t.Errorf("Expected error for invalid characters in %v", r)
+		}
+	}
+	err = s.Redirects([]Redirect{
+		{From: "/old-page.html", To: "/kept.html"},
+		{From: "/kept.html", To: "/elsewhere.html"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.RedirectFiles("netlify", "nginx", "apache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Match
+	for p, to := range map[string]string{
+		"/old-page":           "/kept.html",
+		"/old-page.html":      "/kept.html",
+		"/company":            "/about/",
+		"/company/":           "/about/",
+		"/company/index.html": "/about/",
+		"/about-us":           "/about/",
+		"/products/old":       "/products/new.html",
+		"/draft-old.html":     "",
+		"/missing":            "",
+	} {
+		r, ok, err := s.MatchRedirect(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (to != "") || r.To != to {
+			t.Errorf("Expected %s to redirect to '%s'. Got '%s', %v", p, to, r.To, ok)
+		}
+	}
+
+	// Rules are read again when the public files change
+	publicFS["contact.html"] = &fstest.MapFile{Data: []byte("---\naliases: /contact-us.html\n---\nContact")}
+	if r, ok, err := s.MatchRedirect("/contact-us.html"); err != nil || !ok || r.To != "/contact.html" {
+		t.Errorf("Expected redirect to /contact.html. Got '%s', %v, %v", r.To, ok, err)
+	}
+	delete(publicFS, "contact.html")
+
+	// Ignored files don't change the rules, publishing settings do
+	rules, err := NewIgnoreRules("node_modules/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Ignore(rules)
+	fp, err := s.redirectsFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicFS["node_modules/pkg/index.html"] = &fstest.MapFile{Data: []byte("Package")}
+	if other, err := s.redirectsFingerprint(); err != nil || other != fp {
+		t.Errorf("Expected the same fingerprint with ignored files. Got %s, %v", other, err)
+	}
+	delete(publicFS, "node_modules/pkg/index.html")
+	s.Ignore(nil)
+	s.Drafts(true)
+	if r, ok, err := s.MatchRedirect("/draft-old.html"); err != nil || !ok || r.To != "/draft.html" {
+		t.Errorf("Expected redirect to /draft.html. Got '%s', %v, %v", r.To, ok, err)
+	}
+	s.Drafts(false)
+
+	// Build
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for stub, to := range map[string]string{
+		"old-page.html":      "/kept.html",
+		"company/index.html": "/about/",
+		"about-us.html":      "/about/",
+		"products/old.html":  "/products/new.html",
+	} {
+		content, err := fs.ReadFile(out, stub)
+		if err != nil {
+			t.Errorf("Expected redirect page %s: %s", stub, err)
+			continue
+		}
+		for _, expected := range []string{`<link rel="canonical" href="` + to + `">`, `<meta http-equiv="refresh" content="0; url=` + to + `">`} {
+			if !strings.Contains(string(content), expected) {
+				t.Errorf("Expected %s to contain '%s'. Got '%s'", stub, expected, content)
+			}
+		}
+	}
+
+	// Public files aren't replaced
+	content, err := fs.ReadFile(out, "kept.html")
+	if err != nil || string(content) != "Kept" {
+		t.Errorf("Expected kept.html not replaced by a redirect. Got '%s', %v", content, err)
+	}
+
+	warnings := strings.Join(s.Manifest().Warnings, "\n")
+	if !strings.Contains(warnings, "Redirect from /kept.html skipped") {
+		t.Errorf("Expected warning for skipped redirect page. Got '%s'", warnings)
+	}
+
+	// Rule files
+	for name, expected := range map[string]string{
+		"_redirects":           "/products/old.html /products/new.html 302\n",
+		"redirects.nginx.conf": "location = /company/ { return 301 /about/; }\n",
+		".htaccess":            "RedirectMatch 301 ^/about-us\\.html/?$ /about/\nRedirectMatch 301 ^/company/?$ /about/\n",
+	} {
+		content, err := fs.ReadFile(out, name)
+		if err != nil || !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %s to contain '%s'. Got '%s', %v", name, expected, content, err)
+		}
+	}
+}
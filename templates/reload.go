@@ -0,0 +1,112 @@
+package templates
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// WatchInterval is the time between checks for changes in the templates by Watch().
+var WatchInterval = 500 * time.Millisecond
+
+// Reload parses the templates again from the file systems used by the last Load() or LoadFS() call.
+// The new templates are swapped in only if all of them parse successfully,
+// renders in progress keep using the previous ones.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Reload() error {
+	s.Lock()
+	roots := s.tplRoots
+	s.Unlock()
+	if roots == nil {
+		return NewEmptyTemplateError()
+	}
+
+	return s.LoadFS(roots...)
+}
+
+// Watch checks the loaded templates for changes every WatchInterval and reloads them when any file changes.
+// The result of every reload is sent to the returned channel: nil on success or the error that kept the previous templates.
+// Errors reading the templates are only sent when they change.
+// Results are dropped when the channel isn't read. The channel is closed when ctx is done.
+func (s *Service) Watch(ctx context.Context) <-chan error {
+	results := make(chan error, 1)
+
+	s.Lock()
+	roots := s.tplRoots
+	s.Unlock()
+	last, _ := Fingerprint(roots...)
+	var lastErr string
+
+	go func() {
+		defer close(results)
+
+		ticker := time.NewTicker(WatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				s.Lock()
+				roots = s.tplRoots
+				s.Unlock()
+
+				fp, err := Fingerprint(roots...)
+				if err != nil {
+					if err.Error() == lastErr {
+						continue
+					}
+					lastErr = err.Error()
+				} else {
+					if fp == last {
+						continue
+					}
+					lastErr = ""
+					err = s.Reload()
+				}
+				last = fp
+
+				select {
+				case results <- err:
+				default:
+				}
+			}
+		}
+	}()
+
+	return results
+}
+
+// Fingerprint returns a hash of the names, sizes and modification times of all files in the provided file systems.
+// It changes when any file is added, removed or modified.
+func Fingerprint(roots ...fs.FS) (string, error) {
+	if len(roots) == 0 {
+		return "", NewEmptyTemplateError()
+	}
+
+	sum := sha256.New()
+	for i, root := range roots {
+		err := fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(sum, "%d %s %d %d %s\n", i, name, info.Size(), info.ModTime().UnixNano(), info.Mode())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
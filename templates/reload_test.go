@@ -0,0 +1,153 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	tplFS := fstest.MapFS{
+		"greeting.html": &fstest.MapFile{Data: []byte(`Hello`)},
+	}
+	s, err := LoadFS(tplFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PublicFS(fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`{{ template "greeting.html" }}`)},
+	})
+
+	render := func() string {
+		buff := new(bytes.Buffer)
+		err := s.RenderFile(buff, "index.html", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		return buff.String()
+	}
+
+	if out := render(); out != "Hello" {
+		t.Fatalf("Expected 'Hello'. Got '%s'", out)
+	}
+
+	// Failed reload keeps previous templates
+	tplFS["greeting.html"] = &fstest.MapFile{Data: []byte(`{{ broken`)}
+	err = s.Reload()
+	if err == nil {
+		t.Fatal("Expected reload error")
+	}
+	if out := render(); out != "Hello" {
+		t.Fatalf("Expected previous templates to be kept. Got '%s'", out)
+	}
+
+	// Successful reload while rendering
+	tplFS["greeting.html"] = &fstest.MapFile{Data: []byte(`Bye`)}
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if out := render(); out != "Hello" && out != "Bye" {
+				t.Errorf("Expected 'Hello' or 'Bye'. Got '%s'", out)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := s.Reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if out := render(); out != "Bye" {
+		t.Fatalf("Expected 'Bye'. Got '%s'", out)
+	}
+
+	// Not loaded
+	err = new(Service).Reload()
+	if _, ok := err.(EmptyTemplateError); !ok {
+		t.Errorf("Expected EmptyTemplateError. Got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "greeting.html")
+	err = ioutil.WriteFile(fn, []byte("Hello"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interval := WatchInterval
+	WatchInterval = 10 * time.Millisecond
+	defer func() {
+		WatchInterval = interval
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := s.Watch(ctx)
+
+	err = ioutil.WriteFile(fn, []byte("Bye!"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-results:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected templates to be reloaded")
+	}
+
+	buff := new(bytes.Buffer)
+	err = s.tpl.ExecuteTemplate(buff, "greeting.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buff.String() != "Bye!" {
+		t.Errorf("Expected 'Bye!'. Got '%s'", buff.String())
+	}
+
+	// Read errors are sent once
+	err = os.RemoveAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-results:
+		if err == nil {
+			t.Fatal("Expected error reading removed templates")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected error reading removed templates")
+	}
+	select {
+	case err := <-results:
+		t.Errorf("Expected the same error to be sent once. Got %v", err)
+	case <-time.After(10 * WatchInterval):
+	}
+
+	cancel()
+	for range results {
+	}
+}
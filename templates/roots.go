@@ -0,0 +1,154 @@
+package templates
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ParentPrefix is the template name prefix to call the version of a template replaced by a later template root.
+const ParentPrefix string = "theme:"
+
+// funcs returns the functions bound to the Service, added to every template after FuncMap.
+// Functions bound to a template set are replaced once the set is created.
+func (s *Service) funcs() template.FuncMap {
+	funcs := s.setFuncs(nil, "")
+	funcs["image"] = s.Image
+	funcs["asset"] = s.Asset
+	funcs["frontMatter"] = func() FrontMatter {
+		return FrontMatter{}
+	}
+
+	return funcs
+}
+
+// parse returns a new template set with all templates in the provided roots.
+// Templates in later roots replace the ones with the same name in earlier roots,
+// and references to ParentPrefix+name in them are resolved to the replaced version.
+func (s *Service) parse(roots []fs.FS) (*template.Template, error) {
+	// Init template
+	tpl := template.New("thtml")
+
+	// Add functions
+	tpl.Funcs(FuncMap)
+	tpl.Funcs(s.funcs())
+
+	// Replaced versions by template name, from oldest to newest
+	parents := make(map[string][]string)
+
+	for _, root := range roots {
+		// Parse all files of the root on their own to find the templates they define
+		var trees []*template.Template
+		err := fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if name != "." && s.ignore.Match(name, d.IsDir()) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !s.ValidExtension(path.Ext(name)) {
+				return nil
+			}
+
+			// Load content
+			content, err := fs.ReadFile(root, name)
+			if err != nil {
+				return err
+			}
+
+			// Parse template.
+			file, err := template.New(name).Funcs(FuncMap).Funcs(s.funcs()).Parse(string(content))
+			if err != nil {
+				return err
+			}
+			for _, t := range file.Templates() {
+				if t.Tree != nil {
+					trees = append(trees, t)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, NewError("Error loading templates: " + err.Error())
+		}
+
+		// Keep the versions replaced by this root.
+		// ParentPrefix references in this root resolve to them, or to the current version when not replaced.
+		lower := make(map[string]string)
+		for _, t := range tpl.Templates() {
+			if t.Tree != nil && !strings.HasPrefix(t.Name(), ParentPrefix) {
+				lower[t.Name()] = t.Name()
+			}
+		}
+		for _, t := range trees {
+			old := tpl.Lookup(t.Name())
+			if old == nil || old.Tree == nil || parse.IsEmptyTree(t.Tree.Root) || lower[t.Name()] != t.Name() {
+				continue
+			}
+
+			parent := parentName(t.Name(), len(parents[t.Name()]))
+			_, err = tpl.AddParseTree(parent, old.Tree)
+			if err != nil {
+				return nil, NewError("Error loading templates: " + err.Error())
+			}
+			parents[t.Name()] = append(parents[t.Name()], parent)
+			lower[t.Name()] = parent
+		}
+
+		// Add to the set
+		for _, t := range trees {
+			resolveParents(t.Tree.Root, lower)
+
+			_, err = tpl.AddParseTree(t.Name(), t.Tree)
+			if err != nil {
+				return nil, NewError("Error loading templates: " + err.Error())
+			}
+		}
+	}
+
+	// Bind template set functions
+	tpl.Funcs(s.setFuncs(tpl, ""))
+
+	// Aliases to the version replaced by the last root, for pages and other callers outside the roots
+	names := make([]string, 0, len(parents))
+	for name := range parents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		versions := parents[name]
+		_, err := tpl.AddParseTree(ParentPrefix+name, tpl.Lookup(versions[len(versions)-1]).Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tpl, nil
+}
+
+// parentName returns the internal name of a replaced template version.
+func parentName(name string, version int) string {
+	return ParentPrefix + strconv.Itoa(version) + ":" + name
+}
+
+// resolveParents rewrites the {{ template }} calls using ParentPrefix in the parse tree node
+// to the versions defined in lower roots.
+func resolveParents(node parse.Node, lower map[string]string) {
+	walkTree(node, func(node parse.Node) {
+		n, ok := node.(*parse.TemplateNode)
+		if !ok || !strings.HasPrefix(n.Name, ParentPrefix) {
+			return
+		}
+		if parent, ok := lower[strings.TrimPrefix(n.Name, ParentPrefix)]; ok {
+			n.Name = parent
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package templates
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadRoots(t *testing.T) {
+	vendor := fstest.MapFS{
+		"nav.html":    &fstest.MapFile{Data: []byte(`<nav>vendor</nav>`)},
+		"footer.html": &fstest.MapFile{Data: []byte(`<footer>vendor</footer>`)},
+	}
+	theme := fstest.MapFS{
+		"nav.html":            &fstest.MapFile{Data: []byte(`<div>{{ template "theme:nav.html" }}theme</div>`)},
+		"layouts/base.html":   &fstest.MapFile{Data: []byte(`{{ template "nav.html" }}{{ block "content" . }}base{{ end }}{{ template "footer.html" }}`)},
+		"components/btn.html": &fstest.MapFile{Data: []byte(`{{ define "btn" }}<button>theme</button>{{ end }}`)},
+	}
+	project := fstest.MapFS{
+		"nav.html":     &fstest.MapFile{Data: []byte(`<header>{{ template "theme:nav.html" }}</header>`)},
+		"project.html": &fstest.MapFile{Data: []byte(`{{ define "btn" }}<button>project</button>{{ end }}`)},
+	}
+
+	s, err := LoadRoots([]fs.FS{vendor, theme, project}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PublicFS(fstest.MapFS{
+		"index.html":  &fstest.MapFile{Data: []byte(`{{ template "layouts/base.html" }}{{ template "btn" }}`)},
+		"parent.html": &fstest.MapFile{Data: []byte(`{{ template "theme:nav.html" }}`)},
+	})
+
+	// Later roots override and call the replaced versions
+	buff := new(bytes.Buffer)
+	err = s.RenderFile(buff, "index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<header><div><nav>vendor</nav>theme</div></header>base<footer>vendor</footer><button>project</button>`
+	if buff.String() != expected {
+		t.Errorf("Expected '%s'. Got '%s'", expected, buff.String())
+	}
+
+	// Pages call the version replaced by the last root
+	buff.Reset()
+	err = s.RenderFile(buff, "parent.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `<div><nav>vendor</nav>theme</div>`
+	if buff.String() != expected {
+		t.Errorf("Expected '%s'. Got '%s'", expected, buff.String())
+	}
+
+	// Reload keeps all roots
+	theme["footer.html"] = &fstest.MapFile{Data: []byte(`<footer>theme</footer>`)}
+	err = s.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff.Reset()
+	err = s.RenderFile(buff, "index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buff.String(), "<footer>theme</footer>") {
+		t.Errorf("Expected overridden footer after reload. Got '%s'", buff.String())
+	}
+
+	// Errors in any root fail the load
+	project["broken.html"] = &fstest.MapFile{Data: []byte(`{{ broken`)}
+	_, err = LoadRoots([]fs.FS{vendor, theme, project}, ".html")
+	if err == nil {
+		t.Error("Expected load error")
+	}
+}
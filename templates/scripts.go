@@ -0,0 +1,211 @@
+package templates
+
+import (
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// scriptExts are the extensions of script sources transpiled to ".js", in lookup order.
+var scriptExts = []string{".ts", ".tsx", ".jsx"}
+
+// scriptLoaders are the esbuild loaders of the modules that can be imported from scripts.
+var scriptLoaders = map[string]api.Loader{
+	".ts":   api.LoaderTS,
+	".tsx":  api.LoaderTSX,
+	".jsx":  api.LoaderJSX,
+	".js":   api.LoaderJS,
+	".mjs":  api.LoaderJS,
+	".json": api.LoaderJSON,
+}
+
+// ScriptSource returns the ".ts", ".tsx" or ".jsx" source of the public ".js" file name, if it exists.
+// Declaration files (".d.ts") and private files, named with a leading underscore, aren't sources of scripts,
+// as they're only meant to be imported. Ignored sources aren't either.
+func (s *Service) ScriptSource(name string) (string, bool) {
+	name = path.Clean("/" + name)[1:]
+	if path.Ext(name) != ".js" || s.publicFS == nil || IsPrivate(name) {
+		return "", false
+	}
+
+	base := strings.TrimSuffix(name, ".js")
+	if strings.HasSuffix(base, ".d") {
+		return "", false
+	}
+	for _, ext := range scriptExts {
+		if s.ignore.Ignored(base + ext) {
+			continue
+		}
+		if info, err := fs.Stat(s.publicFS, base+ext); err == nil && !info.IsDir() {
+			return base + ext, true
+		}
+	}
+
+	return "", false
+}
+
+// IsScript returns true if the public ".js" file name, or its ".js.map" source map,
+// is transpiled from a TypeScript or JSX source with the same name.
+func (s *Service) IsScript(name string) bool {
+	_, ok := s.ScriptSource(strings.TrimSuffix(name, ".map"))
+	return ok
+}
+
+// Script transpiles the TypeScript or JSX source of the public ".js" file name,
+// bundling the local modules it imports, and returns the script and its source map.
+// Scripts are minified when enabled.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Script(name string) ([]byte, []byte, error) {
+	name = path.Clean("/" + name)[1:]
+	src, ok := s.ScriptSource(name)
+	if !ok {
+		return nil, nil, NewError("Error compiling script " + name + ": source not found")
+	}
+
+	result := api.Build(api.BuildOptions{
+		EntryPoints:       []string{"/" + src},
+		Outfile:           "/" + name,
+		AbsWorkingDir:     "/",
+		Bundle:            true,
+		Write:             false,
+		Platform:          api.PlatformBrowser,
+		Format:            api.FormatIIFE,
+		Sourcemap:         api.SourceMapLinked,
+		MinifyWhitespace:  s.minify,
+		MinifyIdentifiers: s.minify,
+		MinifySyntax:      s.minify,
+		LogLevel:          api.LogLevelSilent,
+		Plugins:           []api.Plugin{s.scriptPlugin()},
+	})
+	if len(result.Errors) > 0 {
+		msgs := make([]string, len(result.Errors))
+		for i, msg := range result.Errors {
+			msgs[i] = scriptMessage(msg)
+		}
+		return nil, nil, NewError("Error compiling script " + src + ": " + strings.Join(msgs, "; "))
+	}
+
+	var content, sourceMap []byte
+	for _, out := range result.OutputFiles {
+		if strings.HasSuffix(out.Path, ".map") {
+			sourceMap = out.Contents
+		} else {
+			content = out.Contents
+		}
+	}
+
+	return content, sourceMap, nil
+}
+
+// scriptPlugin resolves and loads the modules of scripts from the public file system.
+// Only local modules are supported, imported by paths relative to the importing file or to the public root.
+func (s *Service) scriptPlugin() api.Plugin {
+	return api.Plugin{
+		Name: "public",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				var p string
+				switch {
+				case args.Kind == api.ResolveEntryPoint || strings.HasPrefix(args.Path, "/"):
+					p = path.Clean(args.Path)
+				case strings.HasPrefix(args.Path, "./") || strings.HasPrefix(args.Path, "../"):
+					p = path.Join(path.Dir(args.Importer), args.Path)
+				default:
+					return api.OnResolveResult{}, NewError("Can't import module " + args.Path + ": only local modules are supported")
+				}
+
+				fn, ok := s.resolveModule(p[1:])
+				if !ok {
+					return api.OnResolveResult{}, NewError("Can't find module " + args.Path)
+				}
+				return api.OnResolveResult{Path: "/" + fn, Namespace: "file"}, nil
+			})
+
+			build.OnLoad(api.OnLoadOptions{Filter: ".*"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				content, err := fs.ReadFile(s.publicFS, args.Path[1:])
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				contents := string(content)
+				return api.OnLoadResult{
+					Contents:   &contents,
+					ResolveDir: path.Dir(args.Path),
+					Loader:     scriptLoaders[path.Ext(args.Path)],
+				}, nil
+			})
+		},
+	}
+}
+
+// resolveModule returns the public file of the module imported as name,
+// trying the supported extensions and index files when name doesn't exist as it is.
+// TypeScript sources can also be imported by the name of their transpiled ".js" output.
+func (s *Service) resolveModule(name string) (string, bool) {
+	isFile := func(fn string) bool {
+		info, err := fs.Stat(s.publicFS, fn)
+		return err == nil && !info.IsDir()
+	}
+
+	if _, ok := scriptLoaders[path.Ext(name)]; ok && isFile(name) {
+		return name, true
+	}
+
+	base := name
+	if path.Ext(name) == ".js" {
+		base = strings.TrimSuffix(name, ".js")
+	}
+	for _, ext := range []string{".ts", ".tsx", ".jsx", ".js", ".mjs", ".json"} {
+		if isFile(base + ext) {
+			return base + ext, true
+		}
+	}
+	for _, ext := range []string{".ts", ".tsx", ".jsx", ".js"} {
+		if isFile(path.Join(name, "index"+ext)) {
+			return path.Join(name, "index"+ext), true
+		}
+	}
+
+	return "", false
+}
+
+// scriptMessage formats a compilation error with its location.
+func scriptMessage(msg api.Message) string {
+	if msg.Location == nil {
+		return msg.Text
+	}
+
+	return strings.TrimPrefix(msg.Location.File, "/") + ":" + strconv.Itoa(msg.Location.Line) + ":" +
+		strconv.Itoa(msg.Location.Column+1) + ": " + msg.Text
+}
+
+// buildScript writes the transpiled ".js" file of the script source name and its source map to the build output.
+// Sources that aren't the source of a script, like modules named with a leading underscore, are skipped,
+// as well as sources having a ".js" file with the same name in the public file system.
+func (s *Service) buildScript(name string) error {
+	out := strings.TrimSuffix(name, path.Ext(name)) + ".js"
+	if src, ok := s.ScriptSource(out); !ok || src != name {
+		return nil
+	}
+	if _, err := fs.Stat(s.publicFS, out); err == nil {
+		return nil
+	}
+
+	content, sourceMap, err := s.Script(out)
+	if err != nil {
+		return err
+	}
+
+	err = s.buildFS.WriteFile(out, content, 0644)
+	if err != nil {
+		return err
+	}
+	err = s.buildFS.WriteFile(out+".map", sourceMap, 0644)
+	if err != nil {
+		return err
+	}
+
+	return s.precompress(out, content)
+}
@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScripts(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"js/app.ts":       &fstest.MapFile{Data: []byte("import { greet } from \"./lib/greet\";\nconst name: string = \"World\";\nconsole.log(greet(name));\n")},
+		"js/lib/greet.ts": &fstest.MapFile{Data: []byte("export function greet(name: string): string {\n  return \"Hello \" + name;\n}\n")},
+		"js/view.tsx":     &fstest.MapFile{Data: []byte("const h = (tag: string) => tag;\nconsole.log(<div />);\n")},
+		"js/_private.ts":  &fstest.MapFile{Data: []byte("export const x = 1;\n")},
+		"js/types.d.ts":   &fstest.MapFile{Data: []byte("declare const y: number;\n")},
+		"js/vendor.js":    &fstest.MapFile{Data: []byte("var vendor = 1;\n")},
+		"js/vendor.ts":    &fstest.MapFile{Data: []byte("var vendor = 2;\n")},
+		"js/broken.ts":    &fstest.MapFile{Data: []byte("const a = ;\n")},
+		"js/missing.ts":   &fstest.MapFile{Data: []byte("import \"./none\";\n")},
+		"js/external.ts":  &fstest.MapFile{Data: []byte("import \"react\";\n")},
+		"index.html":      &fstest.MapFile{Data: []byte(`<script src="{{ asset "js/app.js" }}"></script>`)},
+	}
+
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PublicFS(publicFS)
+
+	if !s.IsScript("js/app.js") || !s.IsScript("js/app.js.map") || s.IsScript("js/_private.js") || s.IsScript("js/types.d.js") || s.IsScript("js/none.js") {
+		t.Error("Unexpected IsScript result")
+	}
+
+	content, sourceMap, err := s.Script("js/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"Hello " + name`) || strings.Contains(string(content), ": string") {
+		t.Errorf("Expected transpiled and bundled script. Got '%s'", content)
+	}
+	if !strings.HasSuffix(string(content), "//# sourceMappingURL=app.js.map\n") {
+		t.Errorf("Expected source map URL. Got '%s'", content)
+	}
+	if !strings.Contains(string(sourceMap), `"app.ts"`) || !strings.Contains(string(sourceMap), `"lib/greet.ts"`) {
+		t.Errorf("Expected sources in source map. Got '%s'", sourceMap)
+	}
+
+	content, _, err = s.Script("js/view.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "React.createElement") {
+		t.Errorf("Expected transpiled JSX. Got '%s'", content)
+	}
+
+	// Errors
+	tests := map[string]string{
+		"js/broken.js":   "js/broken.ts:1:11: Unexpected \";\"",
+		"js/missing.js":  "Can't find module ./none",
+		"js/external.js": "only local modules are supported",
+	}
+	for name, expected := range tests {
+		_, _, err = s.Script(name)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error '%s' compiling %s. Got '%v'", expected, name, err)
+		}
+	}
+
+	// Build output
+	delete(publicFS, "js/broken.ts")
+	delete(publicFS, "js/missing.ts")
+	delete(publicFS, "js/external.ts")
+	s.Minify(true)
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"js/app.js", "js/app.js.map", "js/view.js", "js/lib/greet.js"} {
+		if _, err := fs.Stat(out, name); err != nil {
+			t.Errorf("Expected %s in build output: %s", name, err)
+		}
+	}
+	for _, name := range []string{"js/app.ts", "js/_private.js", "js/types.d.js"} {
+		if _, err := fs.Stat(out, name); err == nil {
+			t.Errorf("Unexpected %s in build output", name)
+		}
+	}
+	vendor, err := fs.ReadFile(out, "js/vendor.js")
+	if err != nil || string(vendor) != "var vendor=1" {
+		t.Errorf("Expected public js/vendor.js in build output. Got '%s'", vendor)
+	}
+	index, err := fs.ReadFile(out, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), `src="/js/app.js?v=`) {
+		t.Errorf("Expected versioned asset URL. Got '%s'", index)
+	}
+}
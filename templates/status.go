@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatusPage is the data of status pages, public pages at the root named after an HTTP error status code (i.e. "404.html").
+// They're rendered by the dev server for the responses with their status code,
+// and written to the root of the build output, where static hosts look for them.
+//
+//  <h1>{{ .Status }}</h1>
+//  {{ if .Path }}<p>{{ .Path }} was not found.</p>{{ end }}
+type StatusPage struct {
+	// HTTP status code
+	Status int
+
+	// Requested path and error message, empty in the build output
+	Path  string
+	Error string
+
+	// Data of the request, see server.Options.Data
+	Data interface{}
+}
+
+// IsStatusPage returns the HTTP status code of the public file name if it's a status page:
+// a ".html" page at the root named after a 4xx or 5xx status code.
+func IsStatusPage(name string) (int, bool) {
+	name = path.Clean("/" + name)[1:]
+	if strings.Contains(name, "/") || path.Ext(name) != ".html" {
+		return 0, false
+	}
+
+	code, err := strconv.Atoi(strings.TrimSuffix(name, ".html"))
+	if err != nil || code < 400 || code > 599 {
+		return 0, false
+	}
+	return code, true
+}
+
+// statusPages returns the status pages of the public file system by status code.
+func (s *Service) statusPages() map[int]string {
+	pages := make(map[int]string)
+	if s.publicFS == nil {
+		return pages
+	}
+
+	entries, err := fs.ReadDir(s.publicFS, ".")
+	if err != nil {
+		return pages
+	}
+	for _, e := range entries {
+		if code, ok := IsStatusPage(e.Name()); ok && !e.IsDir() && !s.ignore.Match(e.Name(), false) {
+			pages[code] = e.Name()
+		}
+	}
+
+	return pages
+}
+
+// sortedStatusCodes returns the status codes of the status pages, sorted.
+func sortedStatusCodes(pages map[int]string) []int {
+	codes := make([]int, 0, len(pages))
+	for code := range pages {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
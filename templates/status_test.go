@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIsStatusPage(t *testing.T) {
+	for name, code := range map[string]int{
+		"404.html":      404,
+		"/500.html":     500,
+		"200.html":      0,
+		"404.css":       0,
+		"docs/404.html": 0,
+		"about.html":    0,
+	} {
+		got, ok := IsStatusPage(name)
+		if got != code || ok != (code > 0) {
+			t.Errorf("Expected %s status %d. Got %d, %v", name, code, got, ok)
+		}
+	}
+}
+
+func TestBuildStatusPages(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`Home`)},
+		"404.html":   &fstest.MapFile{Data: []byte(`<h1>{{ .Status }}</h1>{{ if .Path }}{{ .Path }}{{ end }}`)},
+		"500.html":   &fstest.MapFile{Data: []byte(`Error {{ .Status }}`)},
+	}
+
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.RedirectFiles("nginx", "apache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"404.html":             "<h1>404</h1>",
+		"500.html":             "Error 500",
+		"redirects.nginx.conf": "error_page 404 /404.html;\nerror_page 500 /500.html;\n",
+		".htaccess":            "ErrorDocument 404 /404.html\nErrorDocument 500 /500.html\n",
+	} {
+		content, err := fs.ReadFile(out, name)
+		if err != nil || !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %s to contain '%s'. Got '%s', %v", name, expected, content, err)
+		}
+	}
+}
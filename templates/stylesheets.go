@@ -0,0 +1,103 @@
+package templates
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/leonelquinteros/thtml/scss"
+)
+
+// SCSSIncludePaths sets the directories where @use and @import rules of SCSS stylesheets are resolved
+// when not found relative to the importing file.
+func (s *Service) SCSSIncludePaths(dirs ...string) {
+	roots := make([]fs.FS, len(dirs))
+	for i, dir := range dirs {
+		roots[i] = os.DirFS(dir)
+	}
+	s.SCSSIncludeFS(roots...)
+}
+
+// SCSSIncludeFS sets the file systems where @use and @import rules of SCSS stylesheets are resolved
+// when not found relative to the importing file.
+func (s *Service) SCSSIncludeFS(roots ...fs.FS) {
+	s.Lock()
+	s.scssIncludes = roots
+	s.Unlock()
+}
+
+// IsSCSS returns true if the public ".css" file name is compiled from a ".scss" source with the same name.
+// A ".css" file with the same name in the public file system takes precedence over the source,
+// and private or ignored sources aren't compiled.
+func (s *Service) IsSCSS(name string) bool {
+	name = path.Clean("/" + name)[1:]
+	if path.Ext(name) != ".css" || s.publicFS == nil {
+		return false
+	}
+	if _, err := fs.Stat(s.publicFS, name); err == nil {
+		return false
+	}
+
+	src := strings.TrimSuffix(name, ".css") + ".scss"
+	if IsPrivate(src) || s.ignore.Ignored(src) {
+		return false
+	}
+	info, err := fs.Stat(s.publicFS, src)
+	return err == nil && !info.IsDir()
+}
+
+// Stylesheet compiles the ".scss" source of the public ".css" file name, minified when enabled.
+// Compilation errors include the file and line where they happened.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Stylesheet(name string) ([]byte, error) {
+	name = path.Clean("/" + name)[1:]
+	if s.publicFS == nil {
+		return nil, NewError("Error compiling stylesheet " + name + ": public file system not set")
+	}
+	src := strings.TrimSuffix(name, ".css") + ".scss"
+
+	s.Lock()
+	includes := s.scssIncludes
+	s.Unlock()
+
+	css, err := scss.Compile(s.publicFS, src, includes...)
+	if err != nil {
+		return nil, NewError("Error compiling stylesheet " + src + ": " + err.Error())
+	}
+
+	out := new(bytes.Buffer)
+	err = s.flush(out, name, bytes.NewBuffer(css))
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// buildStylesheet writes the compiled ".css" file of the ".scss" source name to the build output.
+// Partials are skipped, as they're only meant to be imported,
+// as well as sources having a ".css" file with the same name in the public file system.
+func (s *Service) buildStylesheet(name string) error {
+	if scss.IsPartial(name) {
+		return nil
+	}
+
+	out := strings.TrimSuffix(name, ".scss") + ".css"
+	if _, err := fs.Stat(s.publicFS, out); err == nil {
+		s.report.warn("Stylesheet " + name + " skipped: " + out + " is in the public files")
+		return nil
+	}
+	content, err := s.Stylesheet(out)
+	if err != nil {
+		return err
+	}
+
+	err = s.buildFS.WriteFile(out, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	return s.precompress(out, content)
+}
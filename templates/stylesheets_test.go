@@ -0,0 +1,85 @@
+package templates
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStylesheets(t *testing.T) {
+	publicFS := fstest.MapFS{
+		"css/site.scss":  &fstest.MapFile{Data: []byte("@use \"vars\";\n@import \"base\";\n.nav {\n  a { color: vars.$primary; }\n}\n")},
+		"css/_base.scss": &fstest.MapFile{Data: []byte("body { margin: 0; }\n")},
+		"index.html":     &fstest.MapFile{Data: []byte(`<link href="{{ asset "css/site.css" }}">`)},
+	}
+
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Minify(true)
+	s.PublicFS(publicFS)
+	s.SCSSIncludeFS(fstest.MapFS{
+		"_vars.scss": &fstest.MapFile{Data: []byte("$primary: #333;\n")},
+	})
+
+	if !s.IsSCSS("css/site.css") || s.IsSCSS("css/_base.css") || s.IsSCSS("css/other.css") {
+		t.Error("Unexpected IsSCSS result")
+	}
+
+	content, err := s.Stylesheet("css/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "body{margin:0}.nav a{color:#333}" {
+		t.Errorf("Expected compiled and minified stylesheet. Got '%s'", content)
+	}
+
+	// Build output
+	out := new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(out, "css/site.css"); err != nil {
+		t.Errorf("Expected css/site.css in build output: %s", err)
+	}
+	for _, name := range []string{"css/site.scss", "css/_base.scss", "css/_base.css"} {
+		if _, err := fs.Stat(out, name); err == nil {
+			t.Errorf("Unexpected %s in build output", name)
+		}
+	}
+	index, err := fs.ReadFile(out, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), `href="/css/site.css?v=`) {
+		t.Errorf("Expected versioned asset URL. Got '%s'", index)
+	}
+
+	// Public ".css" files take precedence over sources with the same name
+	publicFS["css/plain.scss"] = &fstest.MapFile{Data: []byte("a { color: blue; }\n")}
+	publicFS["css/plain.css"] = &fstest.MapFile{Data: []byte("a{color:red}")}
+	if s.IsSCSS("css/plain.css") {
+		t.Error("Expected css/plain.css not compiled")
+	}
+	out = new(MemFS)
+	err = s.BuildFS(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = fs.ReadFile(out, "css/plain.css")
+	if err != nil || string(content) != "a{color:red}" {
+		t.Errorf("Expected public css/plain.css in build output. Got '%s', %v", content, err)
+	}
+	delete(publicFS, "css/plain.scss")
+	delete(publicFS, "css/plain.css")
+
+	// Errors
+	publicFS["css/site.scss"] = &fstest.MapFile{Data: []byte("a {\n  color: $missing;\n}\n")}
+	_, err = s.Stylesheet("css/site.css")
+	if err == nil || !strings.Contains(err.Error(), "css/site.scss:2: Undefined variable $missing") {
+		t.Errorf("Expected error with file and line. Got '%v'", err)
+	}
+}
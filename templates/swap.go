@@ -0,0 +1,192 @@
+package templates
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// previousLayout is the timestamp format in the names of the previous builds kept by BuildDir().
+const previousLayout = "20060102-150405"
+
+// KeepBuilds sets the number of previous build outputs kept by Build() and BuildDir() for rollback,
+// as hidden siblings of the output directory named after it and the time they were replaced (i.e. ".build-20240501-120000").
+// Previous builds are removed when n <= 0.
+func (s *Service) KeepBuilds(n int) {
+	s.keepBuilds = n
+}
+
+// BuildDir compiles all files in the provided input file system into the output directory.
+// The build is written to a temporary sibling directory first, which replaces the output directory only on success,
+// so a failed build keeps the previous output in place. On Linux the directories are exchanged atomically,
+// elsewhere the output directory is missing for the time between two renames.
+// Temporary directories left by interrupted builds are removed.
+// This method is NOT safe to use from multiple/concurrent goroutines
+func (s *Service) BuildDir(in fs.FS, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return NewError("Error locating output directory " + dir + ": " + err.Error())
+	}
+	parent, base := filepath.Dir(abs), filepath.Base(abs)
+
+	// Build into a temporary sibling, on the same file system to rename it
+	err = os.MkdirAll(parent, 0755)
+	if err != nil {
+		return NewError("Error creating output directory " + parent + ": " + err.Error())
+	}
+	stale, err := filepath.Glob(filepath.Join(parent, "."+base+"-tmp-*"))
+	if err != nil {
+		return NewError("Error reading temporary output directories: " + err.Error())
+	}
+	for _, d := range stale {
+		err = os.RemoveAll(d)
+		if err != nil {
+			return NewError("Error removing temporary output directory " + d + ": " + err.Error())
+		}
+	}
+	tmp, err := ioutil.TempDir(parent, "."+base+"-tmp-")
+	if err != nil {
+		return NewError("Error creating temporary output directory: " + err.Error())
+	}
+	err = s.BuildFS(in, DirFS(tmp))
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	err = os.Chmod(tmp, 0755)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return NewError("Error setting output directory permissions: " + err.Error())
+	}
+
+	// Swap
+	if _, err := os.Lstat(abs); err == nil {
+		err = swapDir(tmp, abs, previousName(parent, base))
+		if err != nil {
+			os.RemoveAll(tmp)
+			return NewError("Error replacing output directory " + dir + ": " + err.Error())
+		}
+	} else {
+		err = os.Rename(tmp, abs)
+		if err != nil {
+			os.RemoveAll(tmp)
+			return NewError("Error creating output directory " + dir + ": " + err.Error())
+		}
+	}
+
+	return s.prunePreviousBuilds(parent, base)
+}
+
+// Rollback replaces the output directory with its newest previous build kept by BuildDir(), and returns its path.
+// The replaced output is kept as the newest previous build, so rolling back again restores it.
+func Rollback(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", NewError("Error locating output directory " + dir + ": " + err.Error())
+	}
+	parent, base := filepath.Dir(abs), filepath.Base(abs)
+
+	builds, err := PreviousBuilds(abs)
+	if err != nil {
+		return "", err
+	}
+	if len(builds) == 0 {
+		return "", NewError("Error rolling back " + dir + ": no previous builds")
+	}
+
+	if _, err := os.Lstat(abs); err == nil {
+		err = swapDir(builds[0], abs, previousName(parent, base))
+	} else {
+		err = os.Rename(builds[0], abs)
+	}
+	if err != nil {
+		return "", NewError("Error rolling back " + dir + " to " + builds[0] + ": " + err.Error())
+	}
+
+	return builds[0], nil
+}
+
+// previousName returns an unused name for a previous build of the output directory base, replaced now.
+func previousName(parent, base string) string {
+	prev := filepath.Join(parent, "."+base+"-"+time.Now().Format(previousLayout))
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(prev); os.IsNotExist(err) {
+			return prev
+		}
+		prev = filepath.Join(parent, "."+base+"-"+time.Now().Format(previousLayout)+"-"+strconv.Itoa(i))
+	}
+}
+
+// swapDir replaces the directory abs by tmp, moving it to prev.
+func swapDir(tmp, abs, prev string) error {
+	// Atomic exchange, leaving the previous build in tmp
+	if exchangeDirs(tmp, abs) == nil {
+		return os.Rename(tmp, prev)
+	}
+
+	err := os.Rename(abs, prev)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, abs)
+	if err != nil {
+		// Restore the previous build
+		if rerr := os.Rename(prev, abs); rerr != nil {
+			return NewError(err.Error() + ", and restoring the previous build from " + prev + " failed: " + rerr.Error())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// PreviousBuilds returns the previous outputs of the directory kept by BuildDir(), from newest to oldest.
+func PreviousBuilds(dir string) ([]string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, NewError("Error locating output directory " + dir + ": " + err.Error())
+	}
+	parent, base := filepath.Dir(abs), filepath.Base(abs)
+
+	entries, err := ioutil.ReadDir(parent)
+	if err != nil {
+		return nil, NewError("Error reading previous builds of " + dir + ": " + err.Error())
+	}
+
+	re := regexp.MustCompile(`^\.` + regexp.QuoteMeta(base) + `-\d{8}-\d{6}(-\d+)?$`)
+	var builds []string
+	for _, e := range entries {
+		if e.IsDir() && re.MatchString(e.Name()) {
+			builds = append(builds, filepath.Join(parent, e.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(builds)))
+
+	return builds, nil
+}
+
+// prunePreviousBuilds removes the previous builds beyond the configured number to keep.
+func (s *Service) prunePreviousBuilds(parent, base string) error {
+	builds, err := PreviousBuilds(filepath.Join(parent, base))
+	if err != nil {
+		return err
+	}
+
+	keep := s.keepBuilds
+	if keep < 0 {
+		keep = 0
+	}
+	for i := keep; i < len(builds); i++ {
+		err = os.RemoveAll(builds[i])
+		if err != nil {
+			return NewError("Error removing previous build " + builds[i] + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+package templates
+
+import "golang.org/x/sys/unix"
+
+// exchangeDirs atomically exchanges the directories at the paths a and b.
+func exchangeDirs(a, b string) error {
+	return unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+}
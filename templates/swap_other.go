@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package templates
+
+import "errors"
+
+// exchangeDirs atomically exchanges the directories at the paths a and b. Only supported on Linux.
+func exchangeDirs(a, b string) error {
+	return errors.New("directory exchange not supported")
+}
@@ -0,0 +1,132 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBuildDir(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "build")
+
+	publicFS := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("One")},
+	}
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.KeepBuilds(1)
+
+	// New output
+	err = s.BuildDir(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil || string(content) != "One" {
+		t.Errorf("Expected 'One'. Got '%s', %v", content, err)
+	}
+	info, err := os.Stat(out)
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected output directory with 0755 permissions. Got %v, %v", info, err)
+	}
+
+	// Replaced output keeps the previous one
+	publicFS["index.html"] = &fstest.MapFile{Data: []byte("Two")}
+	err = s.BuildDir(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = ioutil.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil || string(content) != "Two" {
+		t.Errorf("Expected 'Two'. Got '%s', %v", content, err)
+	}
+	prev, err := PreviousBuilds(out)
+	if err != nil || len(prev) != 1 {
+		t.Fatalf("Expected 1 previous build. Got %v, %v", prev, err)
+	}
+	content, err = ioutil.ReadFile(filepath.Join(prev[0], "index.html"))
+	if err != nil || string(content) != "One" {
+		t.Errorf("Expected previous build 'One'. Got '%s', %v", content, err)
+	}
+
+	// Failed build keeps the output in place
+	publicFS["broken.html"] = &fstest.MapFile{Data: []byte("{{ .Missing.Field }")}
+	err = s.BuildDir(publicFS, out)
+	if err == nil {
+		t.Error("Expected error building broken page")
+	}
+	content, err = ioutil.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil || string(content) != "Two" {
+		t.Errorf("Expected 'Two' after failed build. Got '%s', %v", content, err)
+	}
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected output and 1 previous build. Got %d entries", len(entries))
+	}
+
+	// Previous builds and temporary directories of interrupted builds are removed
+	delete(publicFS, "broken.html")
+	s.KeepBuilds(0)
+	err = os.Mkdir(filepath.Join(root, ".build-tmp-123"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.BuildDir(publicFS, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, err = PreviousBuilds(out)
+	if err != nil || len(prev) != 0 {
+		t.Errorf("Expected no previous builds. Got %v, %v", prev, err)
+	}
+	entries, err = ioutil.ReadDir(root)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the output directory. Got %d entries, %v", len(entries), err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "build")
+
+	s, err := LoadFS(fstest.MapFS{}, ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.KeepBuilds(1)
+
+	if _, err := Rollback(out); err == nil {
+		t.Error("Expected error rolling back without previous builds")
+	}
+
+	for _, content := range []string{"One", "Two"} {
+		err = s.BuildDir(fstest.MapFS{"index.html": &fstest.MapFile{Data: []byte(content)}}, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Previous build restored, replaced output kept
+	for _, expected := range []string{"One", "Two"} {
+		_, err = Rollback(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(out, "index.html"))
+		if err != nil || string(content) != expected {
+			t.Errorf("Expected '%s' after rollback. Got '%s', %v", expected, content, err)
+		}
+		prev, err := PreviousBuilds(out)
+		if err != nil || len(prev) != 1 {
+			t.Errorf("Expected 1 previous build. Got %v, %v", prev, err)
+		}
+	}
+}
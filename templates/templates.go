@@ -28,11 +28,85 @@
 //      tplService.Render(os.Stdout, path.Join(_public, "index.html"), nil)
 //  }
 //
+//
+// File systems
+//
+// Templates, public files and the build output can also be provided as file systems,
+// so a site can be embedded with go:embed, rendered from a zip archive (archive/zip.Reader)
+// or tested against testing/fstest.MapFS without touching the disk:
+//
+//  //go:embed site
+//  var site embed.FS
+//
+//  func main() {
+//      tpl, _ := fs.Sub(site, "site/templates")
+//      public, _ := fs.Sub(site, "site/public")
+//
+//      tplService, err := templates.LoadFS(tpl)
+//      if err != nil {
+//          panic(err.Error())
+//      }
+//
+//      out := new(templates.MemFS)
+//      err = tplService.BuildFS(public, out)
+//      if err != nil {
+//          panic(err.Error())
+//      }
+//  }
+//
+//
+// Partial rendering
+//
+// Any loaded template or define block can be rendered on its own with RenderTemplate(),
+// and inline sources can use the loaded templates with RenderString().
+// This allows a Go backend to render HTML fragments (i.e. for htmx partial updates) from the same components as the static website:
+//
+//  tplService.RenderTemplate(w, "components/nav.html", items)
+//  tplService.RenderString(w, `<li>{{ template "item-title" . }}</li>`, item)
+//
+//
+// Components
+//
+// Templates in the "components/" directory can be rendered with named props and slots with the "component" function.
+// Components declare their props with the "props" function, and rendering them without a required prop
+// returns a MissingPropError. See Service.Component() for details:
+//
+//  {{ component "card" (dict "title" "Hello") (dict "default" (partial "card-body" .)) }}
+//
+// CSS and JS files next to a template (i.e. "components/card.css") are bundled for the pages using it,
+// and included with the "componentStyles" and "componentScripts" functions.
+//
+//
+// Template roots
+//
+// Templates can be loaded from multiple directories or file systems with LoadDirs() and LoadRoots().
+// Templates in later roots replace the ones with the same name in earlier roots,
+// and can call the version they replace with the "theme:" prefix:
+//
+//  tplService, err := templates.LoadDirs([]string{"themes/simple/templates", "templates"})
+//
+//  <!-- templates/components/nav.html -->
+//  <div class="wrapper">{{ template "theme:components/nav.html" . }}</div>
+//
+//
+// Hot reload
+//
+// Long-running servers can pick up template changes with Reload(), or let Watch() reload them when files change.
+// New templates are swapped in only after they all parse successfully, so renders in progress keep the previous ones:
+//
+//  for err := range tplService.Watch(ctx) {
+//      if err != nil {
+//          log.Printf("Error reloading templates: %s", err)
+//      }
+//  }
+//
 package templates
 
 import (
+	"crypto/sha256"
 	"bytes"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
@@ -42,13 +116,13 @@ import (
 	"text/template"
 
 	"github.com/tdewolff/minify/v2"
-	"github.com/tdewolff/minify/v2/css"
-	"github.com/tdewolff/minify/v2/html"
-	"github.com/tdewolff/minify/v2/js"
 )
 
 const (
 	defaultExtensions string = ".html"
+
+	// Name of the templates parsed by RenderString()
+	inlineName string = "<string>"
 )
 
 // Service is the template handler.
@@ -59,35 +133,130 @@ type Service struct {
 	// Filename extensions supported
 	exts []string
 
-	// Templates directory
-	tplDir string
-
-	// Build input directory
+	// Build input
+	publicFS  fs.FS
 	publicDir string
 
-	// Build output directory
-	buildDir string
+	// Build output
+	buildFS WriteFS
+
+	// Processed assets cache directory
+	cacheDir string
+
+	// URL path prepended to the generated asset URLs, when the site isn't served from the root
+	basePath string
+
+	// Processed assets to be written to the build output, by output name
+	generated map[string]string
+
+	// Bundle sources patterns, by output name
+	bundles map[string][]string
+
+	// SCSS include paths
+	scssIncludes []fs.FS
+
+	// Build pages by publishing status
+	drafts  bool
+	future  bool
+	expired bool
+
+	// Pages left out of the last build by their publishing status
+	skipped []SkippedPage
+
+	// Files left out of the public and templates file systems
+	ignore *IgnoreRules
+
+	// Configured redirect rules, and formats of the redirect rule files to build
+	redirects       []Redirect
+	redirectFormats []string
+
+	// Redirect rules of the public file system, reused while its fingerprint doesn't change
+	publicRedirects *publicRedirects
+
+	// Number of previous build outputs kept by BuildDir()
+	keepBuilds int
+
+	// Build all files after errors, and the errors of the build in progress
+	keepGoing bool
+	failed    []BuildError
+
+	// Report of the build in progress, and manifest of the last build
+	report   *buildReport
+	manifest *Manifest
 
 	// Minify output
 	minify bool
 
+	// Minifier configured by MinifyOptions()
+	minifier      *minify.M
+	minifyExclude []string
+
+	// Precompressed outputs configuration
+	encodings       []string
+	compressLevel   int
+	compressMinSize int
+
 	// Template wrapper
+	tpl      *template.Template
+	tplRoots []fs.FS
+
+	// Parsed public pages, by name
+	pages map[string]page
+}
+
+// page is a parsed public page, reused while its content doesn't change.
+type page struct {
+	sum [sha256.Size]byte
 	tpl *template.Template
 }
 
 // Load creates a new *templates.Service object and loads the templates in the provided directory.
 // Custom set of filename extensions can be supplied
 func Load(dir string, extensions ...string) (*Service, error) {
-	s := new(Service)
+	s := newService(extensions)
 
-	if len(extensions) == 0 {
-		extensions = strings.Split(defaultExtensions, " ")
+	err := s.Load(dir)
+	if err != nil {
+		return nil, err
 	}
-	for _, ext := range extensions {
-		s.AddExtension(ext)
+
+	return s, nil
+}
+
+// LoadFS creates a new *templates.Service object and loads the templates in the provided file system.
+// Custom set of filename extensions can be supplied
+func LoadFS(fsys fs.FS, extensions ...string) (*Service, error) {
+	s := newService(extensions)
+
+	err := s.LoadFS(fsys)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.Load(dir)
+	return s, nil
+}
+
+// LoadDirs creates a new *templates.Service object and loads the templates in the provided directories,
+// where templates in later directories replace the ones with the same name in earlier directories.
+// Custom set of filename extensions can be supplied
+func LoadDirs(dirs []string, extensions ...string) (*Service, error) {
+	s := newService(extensions)
+
+	err := s.LoadDirs(dirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// LoadRoots creates a new *templates.Service object and loads the templates in the provided file systems,
+// where templates in later roots replace the ones with the same name in earlier roots.
+// Custom set of filename extensions can be supplied
+func LoadRoots(roots []fs.FS, extensions ...string) (*Service, error) {
+	s := newService(extensions)
+
+	err := s.LoadFS(roots...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +264,26 @@ func Load(dir string, extensions ...string) (*Service, error) {
 	return s, nil
 }
 
+// New creates a new *templates.Service object without templates,
+// to be configured before loading them with LoadDirs() or LoadFS().
+// Custom set of filename extensions can be supplied
+func New(extensions ...string) *Service {
+	return newService(extensions)
+}
+
+func newService(extensions []string) *Service {
+	s := new(Service)
+
+	if len(extensions) == 0 {
+		extensions = strings.Split(defaultExtensions, " ")
+	}
+	for _, ext := range extensions {
+		s.AddExtension(ext)
+	}
+
+	return s
+}
+
 // Minify sets the configuration to minify the output
 func (s *Service) Minify(m bool) {
 	s.minify = m
@@ -154,204 +343,432 @@ func (s *Service) ValidExtension(ext string) bool {
 }
 
 // Load takes a directory path and loads all templates on it.
-// This method is NOT safe to use from multiple/concurrent goroutines
+// This method is safe to use from multiple/concurrent goroutines
 func (s *Service) Load(dir string) error {
-	var err error
+	return s.LoadDirs(dir)
+}
 
-	// Parse dir name
-	s.tplDir, err = filepath.Abs(dir)
-	if err != nil {
-		return NewError("Error locating template directory " + dir + ": " + err.Error())
+// LoadDirs loads all templates in the provided directories.
+// Templates in later directories replace the ones with the same name in earlier directories,
+// see LoadFS() for details.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) LoadDirs(dirs ...string) error {
+	roots := make([]fs.FS, len(dirs))
+	for i, dir := range dirs {
+		// Parse dir name
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return NewError("Error locating template directory " + dir + ": " + err.Error())
+		}
+		roots[i] = os.DirFS(abs)
 	}
 
-	// Init template
-	s.tpl = template.New(s.tplDir)
-
-	// Add functions
-	s.tpl.Funcs(FuncMap)
+	return s.LoadFS(roots...)
+}
 
-	// Load
-	err = filepath.Walk(s.tplDir, s.loadFn)
+// LoadFS loads all templates in the provided file systems.
+// Templates are named after their slash-separated path in the file system (i.e. "layouts/default.html").
+//
+// Multiple roots can be layered, i.e. a theme or vendor component library first and then project overrides.
+// A template in a later root replaces the template with the same name in earlier roots,
+// and can call the version it replaces with the "theme:" prefix: {{ template "theme:components/nav.html" . }}
+//
+// The loaded templates are replaced only if all of them parse successfully.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) LoadFS(roots ...fs.FS) error {
+	tpl, err := s.parse(roots)
 	if err != nil {
-		return NewError("Error loading templates from " + dir + ": " + err.Error())
+		return err
 	}
 
+	// Swap
+	s.Lock()
+	s.tplRoots = roots
+	s.tpl = tpl
+	s.pages = nil
+	s.Unlock()
+
 	return nil
 }
 
-func (s *Service) loadFn(path string, info os.FileInfo, err error) error {
-	if !info.IsDir() && s.ValidExtension(filepath.Ext(path)) {
-		// Load content
-		content, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
-		}
+// Render compiles the provided template filename in the loaded templates and writes the output to the provided io.Writer.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) Render(w io.Writer, filename string, data interface{}) error {
+	// Load content
+	fn, err := filepath.Abs(filename)
+	if err != nil {
+		return NewError("Error locating template " + filename + ": " + err.Error())
+	}
+	content, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return NewError("Error reading template " + filename + ": " + err.Error())
+	}
 
-		// Set tpl name
-		n := strings.TrimPrefix(path, s.tplDir)
-		for len(n) > 0 && n[0] == '/' {
-			n = n[1:]
+	// Name files in the public directory after their public path
+	name := filepath.ToSlash(fn)
+	if s.publicDir != "" {
+		if rel, err := filepath.Rel(s.publicDir, fn); err == nil && !strings.HasPrefix(rel, "..") {
+			name = "/" + filepath.ToSlash(rel)
 		}
+	}
 
-		// Load template.
-		_, err = s.tpl.New(n).Parse(string(content))
-		if err != nil {
-			return err
-		}
+	return s.render(w, name, content, data)
+}
+
+// RenderFile compiles the named file from the public file system in the loaded templates
+// and writes the output to the provided io.Writer.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) RenderFile(w io.Writer, name string, data interface{}) error {
+	if s.publicFS == nil {
+		return NewError("Error reading template " + name + ": public file system not set")
 	}
 
-	return nil
+	content, err := fs.ReadFile(s.publicFS, name)
+	if err != nil {
+		return NewError("Error reading template " + name + ": " + err.Error())
+	}
+
+	return s.render(w, "/"+name, content, data)
 }
 
-// Render compiles the provided template filename in the loaded templates and writes the output to the provided io.Writer.
+// RenderTemplate executes a loaded template (i.e. "components/nav.html") or a named define block with the provided data
+// and writes the output to the provided io.Writer.
+// Templates with a minifiable filename extension are minified as the rest of the output.
 // This method is safe to use from multiple/concurrent goroutines
-func (s *Service) Render(w io.Writer, filename string, data interface{}) error {
-	// Check load
+func (s *Service) RenderTemplate(w io.Writer, name string, data interface{}) error {
 	s.Lock()
-	empty := (s.tpl == nil)
+	tpl := s.tpl
 	s.Unlock()
-	if empty {
+	if tpl == nil {
 		return NewEmptyTemplateError()
 	}
 
-	// Load content
-	fn, err := filepath.Abs(filename)
+	if tpl.Lookup(name) == nil {
+		return NewError("Error executing template " + name + ": template not found")
+	}
+
+	buff := new(bytes.Buffer)
+	err := tpl.ExecuteTemplate(buff, name, data)
 	if err != nil {
-		return NewError("Error locating template " + filename + ": " + err.Error())
+		return NewError("Error executing template " + name + ": " + err.Error())
 	}
-	content, err := ioutil.ReadFile(fn)
+
+	return s.flush(w, name, buff)
+}
+
+// RenderString parses src as a template in the context of the loaded templates, executes it with the provided data
+// and writes the output to the provided io.Writer.
+// The output isn't minified.
+// This method is safe to use from multiple/concurrent goroutines
+func (s *Service) RenderString(w io.Writer, src string, data interface{}) error {
+	s.Lock()
+	tpl := s.tpl
+	s.Unlock()
+	if tpl == nil {
+		return NewEmptyTemplateError()
+	}
+
+	tmpTpl, err := tpl.Clone()
 	if err != nil {
-		return NewError("Error reading template " + filename + ": " + err.Error())
+		return NewError("Error cloning template " + inlineName + ": " + err.Error())
+	}
+	tmpTpl.Funcs(s.setFuncs(tmpTpl, inlineName))
+
+	_, err = tmpTpl.New(inlineName).Parse(src)
+	if err != nil {
+		return NewError("Error parsing template " + inlineName + ": " + err.Error())
 	}
 
-	// Create buffer
 	buff := new(bytes.Buffer)
+	err = tmpTpl.ExecuteTemplate(buff, inlineName, data)
+	if err != nil {
+		return NewError("Error executing template " + inlineName + ": " + err.Error())
+	}
 
-	if s.ValidExtension(filepath.Ext(fn)) {
-		// Copy template object
-		s.Lock()
-		tmpTpl, err := s.tpl.Clone()
-		s.Unlock()
-		if err != nil {
-			return NewError("Error cloning template " + filename + ": " + err.Error())
-		}
+	return s.flush(w, inlineName, buff)
+}
+
+// render executes content as a template named name and writes the minified output to w.
+// Names of files in the public directory start with a slash.
+func (s *Service) render(w io.Writer, name string, content []byte, data interface{}) error {
+	// Check load
+	s.Lock()
+	empty := (s.tpl == nil)
+	s.Unlock()
+	if empty {
+		return NewEmptyTemplateError()
+	}
 
-		// Parse template
-		_, err = tmpTpl.New(fn).Parse(string(content))
+	// Create buffer
+	buff := new(bytes.Buffer)
+
+	if s.ValidExtension(path.Ext(name)) {
+		tmpTpl, err := s.parsePage(name, content)
 		if err != nil {
-			return NewError("Error parsing template " + filename + ": " + err.Error())
+			return err
 		}
 
 		// Execute template
-		err = tmpTpl.ExecuteTemplate(buff, fn, data)
+		err = tmpTpl.ExecuteTemplate(buff, name, data)
 		if err != nil {
-			return NewError("Error executing template " + filename + ": " + err.Error())
+			return NewError("Error executing template " + name + ": " + err.Error())
 		}
 	} else {
 		buff.Write(content)
 	}
 
-	// Minifier
-	ext := filepath.Ext(fn)
-	var mime string
-	switch ext {
-	case ".js":
-		mime = "text/javascript"
-	case ".css":
-		mime = "text/css"
-	case ".html":
-		mime = "text/html"
-	}
+	return s.flush(w, name, buff)
+}
 
+// flush minifies the output of the named template according to its type and writes it to w.
+func (s *Service) flush(w io.Writer, name string, buff *bytes.Buffer) error {
+	s.report.rawSize(name, buff.Len())
+
+	// Minify
 	result := new(bytes.Buffer)
-	if mime != "" && s.minify {
-		m := minify.New()
-		m.AddFunc("text/css", css.Minify)
-		m.AddFunc("text/javascript", js.Minify)
-		m.AddFunc("application/javascript", js.Minify)
-
-		// Configure HTML minifier
-		m.Add("text/html", &html.Minifier{
-			KeepConditionalComments: true,
-			KeepDocumentTags:        true,
-			KeepEndTags:             true,
-		})
-
-		// Minify
-		err = m.Minify(mime, result, buff)
-		if err != nil {
-			return NewError("Error minifying " + filename + ": " + err.Error())
+	if mime := s.minifyType(name); mime != "" {
+		err := s.getMinifier().Minify(mime, result, buff)
+		if err == minify.ErrNotExist {
+			// Minifier disabled for this media type
+			result.Reset()
+			result.Write(buff.Bytes())
+		} else if err != nil {
+			return NewError("Error minifying " + name + ": " + err.Error())
 		}
 	} else {
 		result.Write(buff.Bytes())
 	}
 
 	// Flush buffer
-	_, err = w.Write(result.Bytes())
+	_, err := w.Write(result.Bytes())
 	if err != nil {
-		return NewError("Error writing template output " + filename + ": " + err.Error())
+		return NewError("Error writing template output " + name + ": " + err.Error())
 	}
 
 	return nil
 }
 
-// Build compiles all files in the provided directory and outputs the results to the build dir.
-// This method is NOT safe to use from multiple/concurrent goroutines
-func (s *Service) Build(in, out string) (err error) {
-	if s.tpl == nil {
-		return NewEmptyTemplateError()
+// parsePage returns the loaded templates with the page content parsed as name.
+// Parsed pages are reused until their content changes or the templates are reloaded.
+// Pages parsed by builds aren't kept, as each of them is rendered once.
+func (s *Service) parsePage(name string, content []byte) (*template.Template, error) {
+	sum := sha256.Sum256(content)
+	s.Lock()
+	p, ok := s.pages[name]
+	s.Unlock()
+	if ok && p.sum == sum {
+		return p.tpl, nil
 	}
 
-	s.publicDir, err = filepath.Abs(in)
+	// Copy template object
+	s.Lock()
+	loaded := s.tpl
+	tmpTpl, err := loaded.Clone()
+	s.Unlock()
 	if err != nil {
-		return NewError("Error locating input directory " + in + ": " + err.Error())
+		return nil, NewError("Error cloning template " + name + ": " + err.Error())
 	}
-	s.buildDir, err = filepath.Abs(out)
+	tmpTpl.Funcs(s.setFuncs(tmpTpl, name))
+
+	// Front matter
+	matter, body, err := ParseFrontMatter(content)
 	if err != nil {
-		return NewError("Error locating output directory " + out + ": " + err.Error())
+		return nil, NewError("Error parsing template " + name + ": " + err.Error())
 	}
+	tmpTpl.Funcs(template.FuncMap{
+		"frontMatter": func() FrontMatter {
+			return matter
+		},
+	})
+
+	// Parse template
+	_, err = tmpTpl.New(name).Parse(string(body))
+	if err != nil {
+		return nil, NewError("Error parsing template " + name + ": " + err.Error())
+	}
+
+	// Cache, unless building or the templates were reloaded meanwhile
+	s.Lock()
+	if s.report == nil && s.tpl == loaded {
+		if s.pages == nil {
+			s.pages = make(map[string]page)
+		}
+		s.pages[name] = page{sum: sum, tpl: tmpTpl}
+	}
+	s.Unlock()
+
+	return tmpTpl, nil
+}
+
+// Build compiles all files in the provided directory and outputs the results to the build dir,
+// replacing it only when the build succeeds. See BuildDir().
+// This method is NOT safe to use from multiple/concurrent goroutines
+func (s *Service) Build(in, out string) error {
+	err := s.Public(in)
+	if err != nil {
+		return err
+	}
+
+	return s.BuildDir(s.publicFS, out)
+}
+
+// KeepGoing sets the configuration to build all files after a file fails to build.
+// Builds then return a BuildErrors error with the errors of all failed files.
+func (s *Service) KeepGoing(k bool) {
+	s.keepGoing = k
+}
+
+// BuildFS compiles all files in the provided input file system and writes the results to the output file system.
+// The output file system is emptied first.
+// This method is NOT safe to use from multiple/concurrent goroutines
+func (s *Service) BuildFS(in fs.FS, out WriteFS) error {
+	if s.tpl == nil {
+		return NewEmptyTemplateError()
+	}
+
+	s.publicFS = in
+
+	// Record the written files
+	s.report = newBuildReport()
+	s.buildFS = &reportFS{WriteFS: out, report: s.report}
+	defer func() {
+		s.report = nil
+	}()
 
 	// Remove existent build
-	err = os.RemoveAll(s.buildDir)
+	err := s.buildFS.RemoveAll(".")
 	if err != nil {
-		return NewError("Error cleaning output directory " + out + ": " + err.Error())
+		return NewError("Error cleaning output: " + err.Error())
 	}
 
+	// Reset processed assets, skipped pages and errors
+	s.Lock()
+	s.generated = nil
+	s.skipped = nil
+	s.Unlock()
+	s.failed = nil
+
 	// Build
-	err = filepath.Walk(s.publicDir, s.buildFn)
+	err = fs.WalkDir(s.publicFS, ".", s.buildFn)
 	if err != nil {
 		return NewError("Error building output: " + err.Error())
 	}
 
-	return nil
-}
-
-func (s *Service) buildFn(filename string, info os.FileInfo, err error) error {
-	// Ensure directories
-	if !info.IsDir() {
-		// Create output
-		in := strings.TrimPrefix(filename, s.publicDir)
-		out, err := filepath.Abs(path.Join(s.buildDir, in))
+	// Write processed assets
+	s.report.begin()
+	for name, cached := range s.generated {
+		content, err := ioutil.ReadFile(cached)
 		if err != nil {
-			return err
+			return NewError("Error reading processed asset " + name + ": " + err.Error())
 		}
-
-		// Recreate directories
-		err = os.MkdirAll(path.Dir(out), 0755)
+		err = writeFile(s.buildFS, strings.TrimPrefix(name, "/"), content)
+		if err != nil {
+			return NewError("Error writing processed asset " + name + ": " + err.Error())
+		}
+		err = s.precompress(strings.TrimPrefix(name, "/"), content)
 		if err != nil {
+			return NewError("Error compressing processed asset " + name + ": " + err.Error())
+		}
+	}
+
+	// Write bundles
+	err = s.buildBundles()
+	if err != nil {
+		if !s.keepGoing {
 			return err
 		}
+		s.failed = append(s.failed, NewBuildError("", err))
+	}
 
-		// Open file
-		f, err := os.OpenFile(out, os.O_RDWR|os.O_CREATE, 0755)
-		if err != nil {
+	// Write redirects
+	err = s.buildRedirects()
+	if err != nil {
+		if !s.keepGoing {
 			return err
 		}
-		defer f.Close()
+		s.failed = append(s.failed, NewBuildError("", err))
+	}
+	if len(s.failed) > 0 {
+		return NewBuildErrors(s.failed)
+	}
+
+	// Write manifest
+	m := s.report.manifest()
+	s.Lock()
+	s.manifest = m
+	s.Unlock()
+
+	return s.writeManifest(out, m)
+}
+
+func (s *Service) buildFn(name string, d fs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	// Skip private and ignored files
+	if name != "." && (IsPrivate(name) || s.ignore.Match(name, d.IsDir())) {
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	}
+
+	// Ensure directories
+	if d.IsDir() {
+		return s.buildFS.MkdirAll(name, 0755)
+	}
+	s.report.begin(name)
 
-		// Render
-		return s.Render(f, filename, nil)
+	err = s.buildFile(name)
+	if err != nil && s.keepGoing {
+		s.failed = append(s.failed, NewBuildError(name, err))
+		return nil
 	}
+	return err
+}
 
-	return nil
+// buildFile writes the output of the public file name to the build output.
+func (s *Service) buildFile(name string) error {
+	// Compile stylesheets
+	if path.Ext(name) == ".scss" {
+		return s.buildStylesheet(name)
+	}
+
+	// Transpile scripts
+	for _, ext := range scriptExts {
+		if path.Ext(name) == ext {
+			return s.buildScript(name)
+		}
+	}
+
+	// Skip unpublished pages
+	ok, err := s.publishable(name)
+	if err != nil || !ok {
+		return err
+	}
+
+	// Render, status pages without a requested path
+	var data interface{}
+	if code, ok := IsStatusPage(name); ok {
+		data = StatusPage{Status: code}
+	}
+	buff := new(bytes.Buffer)
+	err = s.RenderFile(buff, name, data)
+	if err != nil {
+		return err
+	}
+
+	if buff.Len() == 0 && s.ValidExtension(path.Ext(name)) {
+		s.report.warn("Page " + name + " rendered empty")
+	}
+
+	// Write file
+	err = s.buildFS.WriteFile(name, buff.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	// Precompressed siblings
+	return s.precompress(name, buff.Bytes())
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/leonelquinteros/gorand"
 )
@@ -133,3 +134,114 @@ func TestRace(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestPageCache(t *testing.T) {
+	s, err := LoadFS(fstest.MapFS{
+		"greeting.html": &fstest.MapFile{Data: []byte(`Hello`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`{{ template "greeting.html" }}!`)},
+	}
+	s.PublicFS(public)
+
+	buff := new(bytes.Buffer)
+	err = s.RenderFile(buff, "index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := s.pages["/index.html"].tpl
+
+	// Unchanged
+	err = s.RenderFile(buff, "index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.pages["/index.html"].tpl != parsed {
+		t.Error("Expected unchanged page to be reused")
+	}
+
+	// Changed
+	public["index.html"] = &fstest.MapFile{Data: []byte(`{{ template "greeting.html" }}?`)}
+	buff.Reset()
+	err = s.RenderFile(buff, "index.html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.pages["/index.html"].tpl == parsed {
+		t.Error("Expected changed page to be parsed again")
+	}
+	if buff.String() != "Hello?" {
+		t.Errorf("Expected 'Hello?'. Got '%s'", buff.String())
+	}
+
+	// Pages parsed by builds aren't kept
+	public["about.html"] = &fstest.MapFile{Data: []byte(`About`)}
+	err = s.BuildFS(public, new(MemFS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.pages) != 1 {
+		t.Errorf("Expected only the rendered page cached. Got %d pages", len(s.pages))
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	s, err := LoadFS(fstest.MapFS{
+		"components/nav.html":  &fstest.MapFile{Data: []byte(`<nav>{{ block "nav-items" . }}{{ range . }}<a>{{ . }}</a>{{ end }}{{ end }}</nav>`)},
+		"components/card.html": &fstest.MapFile{Data: []byte(`{{ define "card-title" }}<h2>{{ . }}</h2>{{ end }}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		expected string
+	}{
+		{"components/nav.html", []string{"Home", "About"}, "<nav><a>Home</a><a>About</a></nav>"},
+		{"nav-items", []string{"Home"}, "<a>Home</a>"},
+		{"card-title", "Hi", "<h2>Hi</h2>"},
+	}
+	for _, tt := range tests {
+		buff := new(bytes.Buffer)
+		err = s.RenderTemplate(buff, tt.name, tt.data)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if buff.String() != tt.expected {
+			t.Errorf("%s: Expected '%s'. Got '%s'", tt.name, tt.expected, buff.String())
+		}
+	}
+
+	err = s.RenderTemplate(new(bytes.Buffer), "missing.html", nil)
+	if err == nil {
+		t.Error("Expected error on missing template")
+	}
+
+	// Inline sources
+	buff := new(bytes.Buffer)
+	err = s.RenderString(buff, `<div>{{ template "card-title" .Title }}</div>`, map[string]string{"Title": "Inline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buff.String() != "<div><h2>Inline</h2></div>" {
+		t.Errorf("Expected '<div><h2>Inline</h2></div>'. Got '%s'", buff.String())
+	}
+
+	err = s.RenderString(new(bytes.Buffer), `{{ broken`, nil)
+	if err == nil {
+		t.Error("Expected error on invalid inline source")
+	}
+
+	// Not loaded
+	err = new(Service).RenderTemplate(new(bytes.Buffer), "components/nav.html", nil)
+	if _, ok := err.(EmptyTemplateError); !ok {
+		t.Errorf("Expected EmptyTemplateError. Got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package templates
+
+import (
+	"text/template/parse"
+)
+
+// walkTree calls fn for the node and all its descendants in a template parse tree,
+// including the pipelines and arguments of actions.
+func walkTree(node parse.Node, fn func(parse.Node)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		fn(n)
+		for _, c := range n.Nodes {
+			walkTree(c, fn)
+		}
+
+	case *parse.ActionNode:
+		fn(n)
+		walkTree(n.Pipe, fn)
+
+	case *parse.IfNode:
+		fn(n)
+		walkBranch(&n.BranchNode, fn)
+
+	case *parse.RangeNode:
+		fn(n)
+		walkBranch(&n.BranchNode, fn)
+
+	case *parse.WithNode:
+		fn(n)
+		walkBranch(&n.BranchNode, fn)
+
+	case *parse.TemplateNode:
+		fn(n)
+		walkTree(n.Pipe, fn)
+
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		fn(n)
+		for _, c := range n.Cmds {
+			walkTree(c, fn)
+		}
+
+	case *parse.CommandNode:
+		fn(n)
+		for _, a := range n.Args {
+			walkTree(a, fn)
+		}
+
+	case *parse.ChainNode:
+		fn(n)
+		walkTree(n.Node, fn)
+
+	default:
+		if node != nil {
+			fn(node)
+		}
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkTree(n.Pipe, fn)
+	walkTree(n.List, fn)
+	walkTree(n.ElseList, fn)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/thtml/templates"
+)
+
+// Theme package structure
+const (
+	themeTemplates string = "templates"
+	themePublic    string = "public"
+	themeConfig    string = "theme.json"
+)
+
+// themeDir returns the installation directory of the named theme.
+func themeDir(name string) string {
+	return filepath.Join(_themesPath, name)
+}
+
+// checkTheme returns an error when the theme selected in the config isn't installed.
+func checkTheme() error {
+	if _config.Theme == "" {
+		return nil
+	}
+	if info, err := os.Stat(themeDir(_config.Theme)); err != nil || !info.IsDir() {
+		return errors.New("theme '" + _config.Theme + "' is not installed in '" + _themesPath + "'")
+	}
+
+	return nil
+}
+
+// templatesDirs returns the templates directories of the selected theme and the project, in override order.
+func templatesDirs() []string {
+	var dirs []string
+	if _config.Theme != "" {
+		dir := filepath.Join(themeDir(_config.Theme), themeTemplates)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return append(dirs, strings.Split(_templatesPath, ",")...)
+}
+
+// publicFS returns the project's public directory, merged over the public assets of the selected theme.
+func publicFS() fs.FS {
+	public := os.DirFS(_publicPath)
+	if _config.Theme == "" {
+		return public
+	}
+
+	return templates.OverlayFS(os.DirFS(filepath.Join(themeDir(_config.Theme), themePublic)), public)
+}
+
+// runTheme executes the "theme" command with the provided arguments.
+func runTheme(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Missing theme command. Use: theme list | theme add <path> [name] | theme remove <name>")
+	}
+
+	switch args[0] {
+	case "list":
+		names, err := listThemes()
+		if err != nil {
+			log.Fatalf("Error listing themes in '%s': %s", _themesPath, err)
+		}
+		for _, name := range names {
+			if name == _config.Theme {
+				fmt.Println("*", name)
+			} else {
+				fmt.Println(" ", name)
+			}
+		}
+
+	case "add":
+		if len(args) < 2 {
+			log.Fatal("Missing theme path. Use: theme add <path> [name]")
+		}
+		name := ""
+		if len(args) > 2 {
+			name = args[2]
+		}
+		name, err := addTheme(args[1], name)
+		if err != nil {
+			log.Fatalf("Error adding theme from '%s': %s", args[1], err)
+		}
+		log.Printf("Theme '%s' added to '%s'", name, themeDir(name))
+
+	case "remove":
+		if len(args) < 2 {
+			log.Fatal("Missing theme name. Use: theme remove <name>")
+		}
+		err := removeTheme(args[1])
+		if err != nil {
+			log.Fatalf("Error removing theme '%s': %s", args[1], err)
+		}
+		log.Printf("Theme '%s' removed", args[1])
+
+	default:
+		log.Fatalf("Unknown theme command '%s'", args[0])
+	}
+}
+
+// listThemes returns the names of the themes installed in the themes directory.
+func listThemes() ([]string, error) {
+	entries, err := ioutil.ReadDir(_themesPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// addTheme installs the theme in the local directory or zip archive at src into the themes directory.
+// The theme is named after src when name is empty.
+// It returns the name of the installed theme.
+func addTheme(src, name string) (string, error) {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(filepath.Clean(src)), ".zip")
+	}
+	if !validThemeName(name) {
+		return "", errors.New("invalid theme name '" + name + "'")
+	}
+
+	dst := themeDir(name)
+	if _, err := os.Stat(dst); err == nil {
+		return "", errors.New("theme '" + name + "' already exists")
+	}
+
+	// Open source
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	var fsys fs.FS
+	if info.IsDir() {
+		fsys = os.DirFS(src)
+	} else {
+		r, err := zip.OpenReader(src)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		fsys = r
+	}
+
+	// Archives usually wrap the theme in a single directory
+	fsys, err = themeRoot(fsys)
+	if err != nil {
+		return "", err
+	}
+
+	// Copy
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fn := filepath.Join(dst, filepath.FromSlash(p))
+		if d.IsDir() {
+			return os.MkdirAll(fn, 0755)
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fn, content, 0644)
+	})
+	if err != nil {
+		os.RemoveAll(dst)
+		return "", err
+	}
+
+	return name, nil
+}
+
+// themeRoot returns the directory of fsys containing the theme package structure,
+// either at the root or inside a single top-level directory.
+func themeRoot(fsys fs.FS) (fs.FS, error) {
+	isTheme := func(fsys fs.FS) bool {
+		for _, name := range []string{themeTemplates, themePublic, themeConfig} {
+			if _, err := fs.Stat(fsys, name); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+
+	if isTheme(fsys) {
+		return fsys, nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		sub, err := fs.Sub(fsys, entries[0].Name())
+		if err != nil {
+			return nil, err
+		}
+		if isTheme(sub) {
+			return sub, nil
+		}
+	}
+
+	return nil, errors.New("no " + themeTemplates + ", " + themePublic + " or " + themeConfig + " found")
+}
+
+// validThemeName returns true for names of a single directory inside the themes directory.
+func validThemeName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// removeTheme deletes the named theme from the themes directory.
+func removeTheme(name string) error {
+	if !validThemeName(name) {
+		return errors.New("invalid theme name '" + name + "'")
+	}
+
+	dst := themeDir(name)
+	if info, err := os.Stat(dst); err != nil || !info.IsDir() {
+		return errors.New("theme '" + name + "' is not installed")
+	}
+
+	return os.RemoveAll(dst)
+}
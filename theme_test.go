@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThemes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "thtml-themes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_themesPath = filepath.Join(dir, "themes")
+	defer func() {
+		_themesPath = "themes"
+		_config = defaultConfig()
+	}()
+
+	files := map[string]string{
+		"templates/nav.html": `<nav>theme</nav>`,
+		"public/index.html":  `theme index`,
+		"public/css/a.css":   `a{}`,
+		"theme.json":         `{"minify": {"html": {"keepComments": true, "keepWhitespace": true}}}`,
+	}
+
+	// Directory source
+	src := filepath.Join(dir, "simple")
+	for name, content := range files {
+		fn := filepath.Join(src, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(fn), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(fn, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	name, err := addTheme(src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "simple" {
+		t.Errorf("Expected theme name 'simple'. Got '%s'", name)
+	}
+	if _, err = addTheme(src, ""); err == nil {
+		t.Error("Expected error adding an existing theme")
+	}
+
+	// Zip source wrapped in a directory
+	buff := new(bytes.Buffer)
+	zw := zip.NewWriter(buff)
+	for name, content := range files {
+		w, err := zw.Create("wrapped-1.0/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFn := filepath.Join(dir, "wrapped.zip")
+	err = ioutil.WriteFile(zipFn, buff.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err = addTheme(zipFn, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(themeDir(name), "templates", "nav.html")); err != nil {
+		t.Errorf("Expected unwrapped theme templates: %s", err)
+	}
+
+	names, err := listThemes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "simple" || names[1] != "wrapped" {
+		t.Errorf("Expected themes [simple wrapped]. Got %v", names)
+	}
+
+	// Project config overrides theme defaults
+	configFn := filepath.Join(dir, "thtml.json")
+	err = ioutil.WriteFile(configFn, []byte(`{"theme": "simple", "minify": {"html": {"keepWhitespace": false}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_config, err = loadConfig(configFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !_config.Minify.HTML.KeepComments || _config.Minify.HTML.KeepWhitespace {
+		t.Errorf("Expected theme keepComments and project keepWhitespace. Got %+v", _config.Minify.HTML)
+	}
+	if err = checkTheme(); err != nil {
+		t.Error(err)
+	}
+
+	// Project public files win over theme assets
+	project := filepath.Join(dir, "public")
+	err = os.MkdirAll(project, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(project, "index.html"), []byte(`project index`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_publicPath = project
+	defer func() {
+		_publicPath = "public"
+	}()
+	public := publicFS()
+	if content, _ := fs.ReadFile(public, "index.html"); string(content) != "project index" {
+		t.Errorf("Expected project index. Got '%s'", content)
+	}
+	if content, _ := fs.ReadFile(public, "css/a.css"); string(content) != "a{}" {
+		t.Errorf("Expected theme asset. Got '%s'", content)
+	}
+	if dirs := templatesDirs(); len(dirs) != 2 || dirs[0] != filepath.Join(themeDir("simple"), "templates") {
+		t.Errorf("Expected theme templates first. Got %v", dirs)
+	}
+
+	err = removeTheme("wrapped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = removeTheme("wrapped"); err == nil {
+		t.Error("Expected error removing a missing theme")
+	}
+	if err = removeTheme(".."); err == nil {
+		t.Error("Expected error removing an invalid theme name")
+	}
+}